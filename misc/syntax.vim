@@ -1,10 +1,13 @@
 syn keyword hikeInitiator goal artifact file artifacts pipeline exec each regex scandir tree
 syn keyword hikeInitiator delete split set setdef include copy zip piece unzip valve directory
-syn keyword hikeInitiator mkdir
+syn keyword hikeInitiator mkdir depend repository local git tar untar archiveEntry archiveScan symlinkTo link substitute concat write checksums sync stamp
 syn keyword hikeOption label name key base loud suffixIsDestination rebaseFrom rebaseTo noCache
-syn keyword hikeOption toDirectory from to rename
+syn keyword hikeOption toDirectory from to rename ignoreFiles version gzip
+syn keyword hikeOption fixedTime sorted preserveModes skipDirectories method level preserveTimes symlinks skipIdentical cacheDir
+syn keyword hikeOption hard symbolic relative absolute target preserveSymlinks followSymlinks template separator header footer sha256 sha512 exclude skipUnchanged stdoutToDest stdinFromSource stderrTo
 syn keyword hikeModifier merge ifExists
-syn keyword hikeFilter files directories wildcard any all not
+syn keyword hikeFilter files directories wildcard glob any all not
+syn keyword hikeFilter size olderThan newerThan executable symlink empty
 syn keyword hikePlaceholder source dest aux
 syn keyword hikeAction attain require
 syn keyword hikeSetting projectName
@@ -15,6 +18,8 @@ syn match hikeDelimiter /[{}]/
 syn region hikeString start=+"+ end=+"+ skip=+\\.+ contains=hikeEscape,hikeBadEscape
 syn match hikeEscape /\\\%([rntbafve\\"]\|x[0-9a-fA-F]\{2\}\|u[0-9a-fA-F]\{4\}\|U[0-9a-fA-F]\{8\}\)/ contained
 syn match hikeBadEscape /\\[^rntbafve\\"xuU]/ contained
+syn region hikeRawString start=+`+ end=+`+
+syn region hikeHeredoc start=+<<\z(\w\+\)\s*$+ end=+^\s*\z1\s*$+
 
 hi link hikeInitiator Type
 hi link hikeOption PreProc
@@ -27,5 +32,7 @@ hi link hikeSetting hikeModifier
 hi link hikeInt Number
 hi link hikeDelimiter Keyword
 hi link hikeString String
+hi link hikeRawString String
+hi link hikeHeredoc String
 hi link hikeEscape Special
 hi link hikeBadEscape Error
@@ -100,5 +100,42 @@ func IsExecOption(parser *prs.Parser) bool {
 	if parser.Token.Type != tok.T_NAME {
 		return false
 	}
-	return parser.Token.Text == "loud" || parser.Token.Text == "suffixIsDestination"
+	switch parser.Token.Text {
+		case "loud", "suffixIsDestination", "stdoutToDest", "stdinFromSource", "stderrTo":
+			return true
+		default:
+			return false
+	}
+}
+
+func ParseExecStreamOption(
+	parser *prs.Parser,
+	streams *gen.CommandStreams,
+	what string,
+	haveDestination bool,
+) bool {
+	switch parser.Token.Text {
+		case "stdoutToDest":
+			if !haveDestination {
+				parser.Die("exec option valid without a destination")
+				return false
+			}
+			streams.StdoutToDest = true
+			parser.Next()
+		case "stdinFromSource":
+			streams.StdinFromSource = true
+			parser.Next()
+		case "stderrTo":
+			location := &parser.Token.Location
+			parser.Next()
+			if !parser.ExpectExp(tok.T_STRING, "standard error file path") {
+				parser.Frame(what + " 'stderrTo' option", location)
+				return false
+			}
+			streams.StderrTo = parser.SpecState().Config.RealPath(parser.InterpolateString())
+			parser.Next()
+		default:
+			panic("Unrecognized exec option: " + parser.Token.Text)
+	}
+	return true
 }
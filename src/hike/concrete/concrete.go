@@ -169,6 +169,7 @@ type FileArtifact struct {
 	ArtifactBase
 	Path string
 	GeneratingTransform abs.Transform
+	SHA256 string
 }
 
 func (artifact *FileArtifact) DisplayName() string {
@@ -185,6 +186,9 @@ func (artifact *FileArtifact) PathNames(sink []string) ([]string, herr.BuildErro
 
 func (artifact *FileArtifact) ModTime(arise *herr.AriseRef) (stamp time.Time, err herr.BuildError, missing bool) {
 	info, oserr := os.Stat(artifact.Path)
+	if os.IsNotExist(oserr) {
+		info, oserr = os.Lstat(artifact.Path)
+	}
 	if oserr == nil {
 		stamp = info.ModTime()
 	} else {
@@ -231,6 +235,7 @@ func (artifact *FileArtifact) Require(plan *abs.Plan, requireArise *herr.AriseRe
 	if plan.AlreadyUpToDate(artifact) {
 		return
 	}
+	stepCount := plan.StepCount()
 	if artifact.GeneratingTransform != nil {
 		err = artifact.GeneratingTransform.Plan(artifact, plan)
 		if err != nil {
@@ -253,6 +258,14 @@ func (artifact *FileArtifact) Require(plan *abs.Plan, requireArise *herr.AriseRe
 				}
 		}
 	}
+	if err == nil && len(artifact.SHA256) > 0 {
+		err = PlanFileChecksum(artifact, artifact.Path, "sha256", artifact.SHA256, plan, stepCount, requireArise)
+		if err != nil {
+			err.AddErrorFrame(&RequireArtifactFrame {
+				Artifact: artifact,
+			})
+		}
+	}
 	if err == nil {
 		plan.BroughtUpToDate(artifact)
 	}
@@ -273,6 +286,12 @@ func (artifact *FileArtifact) DumpArtifact(level uint) error {
 	prn.Print("name ")
 	PrintErrorString(prn, artifact.Name)
 	prn.Println()
+	if len(artifact.SHA256) > 0 {
+		prn.Indent(1)
+		prn.Print("sha256 ")
+		PrintErrorString(prn, artifact.SHA256)
+		prn.Println()
+	}
 	if artifact.GeneratingTransform != nil {
 		prn.Indent(1)
 		artifact.GeneratingTransform.DumpTransform(level + 1)
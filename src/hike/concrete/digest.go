@@ -0,0 +1,216 @@
+package concrete
+
+import (
+	"os"
+	"io"
+	"fmt"
+	"hash"
+	"strings"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	herr "hike/error"
+	loc "hike/location"
+	abs "hike/abstract"
+)
+
+// ---------------------------------------- BuildError ----------------------------------------
+
+type ChecksumMismatchError struct {
+	herr.BuildErrorBase
+	Artifact abs.Artifact
+	Path string
+	Algorithm string
+	Expected string
+	Actual string
+	RequireArise *herr.AriseRef
+}
+
+func (mismatch *ChecksumMismatchError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Printf("Checksum mismatch (%s) for file\n", mismatch.Algorithm)
+	prn.Indent(1)
+	prn.Println(mismatch.Path)
+	prn.Indent(0)
+	prn.Printf("of artifact %s [%s]\n", mismatch.Artifact.DisplayName(), mismatch.Artifact.ArtifactKey().Unified())
+	prn.Indent(0)
+	prn.Arise(mismatch.Artifact.ArtifactArise(), 0)
+	prn.Println()
+	prn.Indent(0)
+	prn.Println("expected:", mismatch.Expected)
+	prn.Indent(0)
+	prn.Println("actual:  ", mismatch.Actual)
+	prn.Indent(0)
+	prn.Print("for requisition ")
+	prn.Arise(mismatch.RequireArise, 0)
+	mismatch.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (mismatch *ChecksumMismatchError) BuildErrorLocation() *loc.Location {
+	return mismatch.Artifact.ArtifactArise().Location
+}
+
+var _ herr.BuildError = &ChecksumMismatchError{}
+
+type DigestFileError struct {
+	herr.BuildErrorBase
+	Path string
+	OSError error
+	OperationArise *herr.AriseRef
+}
+
+func (digest *DigestFileError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Println("Failed to compute checksum of file")
+	prn.Indent(1)
+	prn.Println(digest.Path)
+	prn.Indent(0)
+	prn.Print("in operation ")
+	prn.Arise(digest.OperationArise, 0)
+	prn.Println()
+	prn.Indent(0)
+	prn.Printf("because: %s", digest.OSError.Error())
+	digest.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (digest *DigestFileError) BuildErrorLocation() *loc.Location {
+	return digest.OperationArise.Location
+}
+
+var _ herr.BuildError = &DigestFileError{}
+
+type IllegalChecksumError struct {
+	herr.BuildErrorBase
+	Algorithm string
+	Checksum string
+	Location *loc.Location
+}
+
+func (illegal *IllegalChecksumError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Printf("Illegal %s checksum '%s' at ", illegal.Algorithm, illegal.Checksum)
+	prn.Location(illegal.Location)
+	illegal.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (illegal *IllegalChecksumError) BuildErrorLocation() *loc.Location {
+	return illegal.Location
+}
+
+var _ herr.BuildError = &IllegalChecksumError{}
+
+// ---------------------------------------- Digest ----------------------------------------
+
+func DigestFunction(algorithm string) func() hash.Hash {
+	switch algorithm {
+		case "sha256":
+			return sha256.New
+		case "sha512":
+			return sha512.New
+		default:
+			return nil
+	}
+}
+
+func IsValidChecksum(algorithm string, checksum string) bool {
+	newHash := DigestFunction(algorithm)
+	if newHash == nil {
+		return false
+	}
+	raw, err := hex.DecodeString(checksum)
+	return err == nil && len(raw) == newHash().Size()
+}
+
+func FileDigest(path string, algorithm string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hasher := DigestFunction(algorithm)()
+	_, err = io.Copy(hasher, file)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func VerifyFileChecksum(
+	artifact abs.Artifact,
+	path string,
+	algorithm string,
+	expected string,
+	arise *herr.AriseRef,
+) herr.BuildError {
+	actual, err := FileDigest(path, algorithm)
+	if err != nil {
+		return &DigestFileError {
+			Path: path,
+			OSError: err,
+			OperationArise: arise,
+		}
+	}
+	if actual != strings.ToLower(expected) {
+		return &ChecksumMismatchError {
+			Artifact: artifact,
+			Path: path,
+			Algorithm: algorithm,
+			Expected: strings.ToLower(expected),
+			Actual: actual,
+			RequireArise: arise,
+		}
+	}
+	return nil
+}
+
+// ---------------------------------------- Step ----------------------------------------
+
+type VerifyChecksumStep struct {
+	StepBase
+	Artifact abs.Artifact
+	Path string
+	Algorithm string
+	Expected string
+	Arise *herr.AriseRef
+}
+
+func (step *VerifyChecksumStep) Perform() herr.BuildError {
+	return VerifyFileChecksum(step.Artifact, step.Path, step.Algorithm, step.Expected, step.Arise)
+}
+
+var _ abs.Step = &VerifyChecksumStep{}
+
+func PlanFileChecksum(
+	artifact abs.Artifact,
+	path string,
+	algorithm string,
+	expected string,
+	plan *abs.Plan,
+	stepCount int,
+	arise *herr.AriseRef,
+) herr.BuildError {
+	if plan.StepCount() == stepCount {
+		return VerifyFileChecksum(artifact, path, algorithm, expected, arise)
+	}
+	step := &VerifyChecksumStep {
+		Artifact: artifact,
+		Path: path,
+		Algorithm: algorithm,
+		Expected: expected,
+		Arise: arise,
+	}
+	step.Description = fmt.Sprintf(
+		"[%s] verify %s %s",
+		artifact.ArtifactKey().Project,
+		algorithm,
+		artifact.DisplayName(),
+	)
+	plan.AddStep(step)
+	return nil
+}
@@ -191,3 +191,20 @@ func (conflict *ConflictingDestinationsError) BuildErrorLocation() *loc.Location
 }
 
 var _ herr.BuildError = &ConflictingDestinationsError{}
+
+func SinglePath(
+	paths []string,
+	operation string,
+	isDestination bool,
+	arise *herr.AriseRef,
+) (string, herr.BuildError) {
+	if len(paths) != 1 {
+		return "", &ConflictingDestinationsError {
+			Operation: operation,
+			OperationArise: arise,
+			PathCount: uint(len(paths)),
+			PathsAreDestinations: isDestination,
+		}
+	}
+	return paths[0], nil
+}
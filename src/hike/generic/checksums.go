@@ -0,0 +1,139 @@
+package generic
+
+import (
+	"os"
+	"fmt"
+	"sort"
+	"strings"
+	"path/filepath"
+	herr "hike/error"
+	abs "hike/abstract"
+	con "hike/concrete"
+)
+
+// ---------------------------------------- Transform ----------------------------------------
+
+var checksumNameEscaper = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r")
+
+func ChecksumLine(digest string, name string) string {
+	escaped := checksumNameEscaper.Replace(name)
+	if escaped != name {
+		return fmt.Sprintf("\\%s  %s\n", digest, escaped)
+	}
+	return fmt.Sprintf("%s  %s\n", digest, name)
+}
+
+type ChecksumsTransform struct {
+	con.MultiTransformBase
+	Algorithm string
+	RebaseFrom string
+	UIBase string
+}
+
+func NewChecksumsTransform(arise *herr.AriseRef, algorithm string, uiBase string) *ChecksumsTransform {
+	transform := &ChecksumsTransform {
+		Algorithm: algorithm,
+		UIBase: uiBase,
+	}
+	transform.Description = "checksums"
+	transform.Arise = arise
+	return transform
+}
+
+func (xform *ChecksumsTransform) baseDir(manifest string) string {
+	if len(xform.RebaseFrom) > 0 {
+		return xform.RebaseFrom
+	} else {
+		return filepath.Dir(manifest)
+	}
+}
+
+func (xform *ChecksumsTransform) Render(manifest string) ([]byte, herr.BuildError) {
+	paths, err := con.PathsOfArtifacts(xform.Sources)
+	if err != nil {
+		return nil, err
+	}
+	base := xform.baseDir(manifest)
+	lines := make(map[string]string)
+	var names []string
+	for _, path := range paths {
+		if path == manifest {
+			continue
+		}
+		info, oserr := os.Stat(path)
+		if oserr == nil && info.IsDir() {
+			continue
+		}
+		var digest string
+		if oserr == nil {
+			digest, oserr = con.FileDigest(path, xform.Algorithm)
+		}
+		if oserr != nil {
+			return nil, &con.DigestFileError {
+				Path: path,
+				OSError: oserr,
+				OperationArise: xform.Arise,
+			}
+		}
+		name, rerr := filepath.Rel(base, path)
+		if rerr != nil {
+			name = path
+		}
+		name = filepath.ToSlash(name)
+		_, seen := lines[name]
+		if !seen {
+			names = append(names, name)
+		}
+		lines[name] = ChecksumLine(digest, name)
+	}
+	sort.Strings(names)
+	var sink strings.Builder
+	for _, name := range names {
+		sink.WriteString(lines[name])
+	}
+	return []byte(sink.String()), nil
+}
+
+func (xform *ChecksumsTransform) Plan(destination abs.Artifact, plan *abs.Plan) herr.BuildError {
+	return PlanContentTransform(
+		xform,
+		xform.Sources,
+		destination,
+		plan,
+		xform.Render,
+		func(dest string) string {
+			paths, _ := con.PathsOfArtifacts(xform.Sources)
+			return fmt.Sprintf(
+				"%s %s -> %s",
+				xform.Algorithm,
+				filepath.ToSlash(con.RelPath(con.GuessGroupArtifactNameNat(paths, xform.baseDir(dest)), xform.UIBase)),
+				filepath.ToSlash(con.RelPath(dest, xform.UIBase)),
+			)
+		},
+	)
+}
+
+func (xform *ChecksumsTransform) DumpTransform(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Out = os.Stdout
+	prn.Level(level)
+	prn.Println("checksums {")
+	for _, source := range xform.Sources {
+		prn.Indent(1)
+		con.PrintErrorString(prn, source.ArtifactKey().Unified())
+		prn.Println()
+	}
+	prn.Indent(1)
+	prn.Println(xform.Algorithm)
+	if len(xform.RebaseFrom) > 0 {
+		prn.Indent(1)
+		prn.Print("rebaseFrom ")
+		con.PrintErrorString(prn, xform.RebaseFrom)
+		prn.Println()
+	}
+	prn.Indent(0)
+	prn.Print("}")
+	return prn.Done()
+}
+
+var _ abs.Transform = &ChecksumsTransform{}
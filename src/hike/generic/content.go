@@ -0,0 +1,322 @@
+package generic
+
+import (
+	"os"
+	"fmt"
+	"bytes"
+	"path/filepath"
+	herr "hike/error"
+	loc "hike/location"
+	abs "hike/abstract"
+	con "hike/concrete"
+)
+
+// ---------------------------------------- BuildError ----------------------------------------
+
+type WriteFileError struct {
+	herr.BuildErrorBase
+	Path string
+	OSError error
+	OperationArise *herr.AriseRef
+}
+
+func (write *WriteFileError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Println("Failed to write file")
+	prn.Indent(1)
+	prn.Println(write.Path)
+	prn.Indent(0)
+	prn.Print("in operation ")
+	prn.Arise(write.OperationArise, 0)
+	prn.Println()
+	prn.Indent(0)
+	prn.Printf("because: %s", write.OSError.Error())
+	write.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (write *WriteFileError) BuildErrorLocation() *loc.Location {
+	return write.OperationArise.Location
+}
+
+var _ herr.BuildError = &WriteFileError{}
+
+type ReadFileError struct {
+	herr.BuildErrorBase
+	Path string
+	OSError error
+	OperationArise *herr.AriseRef
+}
+
+func (read *ReadFileError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Println("Failed to read file")
+	prn.Indent(1)
+	prn.Println(read.Path)
+	prn.Indent(0)
+	prn.Print("in operation ")
+	prn.Arise(read.OperationArise, 0)
+	prn.Println()
+	prn.Indent(0)
+	prn.Printf("because: %s", read.OSError.Error())
+	read.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (read *ReadFileError) BuildErrorLocation() *loc.Location {
+	return read.OperationArise.Location
+}
+
+var _ herr.BuildError = &ReadFileError{}
+
+// ---------------------------------------- Step ----------------------------------------
+
+func ContentDiffers(path string, content []byte) bool {
+	current, err := os.ReadFile(path)
+	return err != nil || !bytes.Equal(current, content)
+}
+
+func WriteIfChanged(path string, content []byte, arise *herr.AriseRef) herr.BuildError {
+	if !ContentDiffers(path, content) {
+		return nil
+	}
+	err := con.MakeEnclosingDirectories(path, arise)
+	if err != nil {
+		return err
+	}
+	oserr := os.WriteFile(path, content, 0666)
+	if oserr != nil {
+		return &WriteFileError {
+			Path: path,
+			OSError: oserr,
+			OperationArise: arise,
+		}
+	}
+	return nil
+}
+
+type ContentStep struct {
+	con.StepBase
+	Destination string
+	Render func() ([]byte, herr.BuildError)
+	Arise *herr.AriseRef
+}
+
+func (step *ContentStep) Perform() herr.BuildError {
+	content, err := step.Render()
+	if err != nil {
+		return err
+	}
+	return WriteIfChanged(step.Destination, content, step.Arise)
+}
+
+var _ abs.Step = &ContentStep{}
+
+// ---------------------------------------- Transform ----------------------------------------
+
+func planContent(
+	transform abs.Transform,
+	sources []abs.Artifact,
+	destination abs.Artifact,
+	plan *abs.Plan,
+	render func(dest string) ([]byte, herr.BuildError),
+	describe func(dest string) string,
+) herr.BuildError {
+	stepCount := plan.StepCount()
+	for _, source := range sources {
+		err := source.Require(plan, transform.TransformArise())
+		if err != nil {
+			return err
+		}
+	}
+	destPaths, err := destination.PathNames(nil)
+	if err != nil {
+		return err
+	}
+	if len(destPaths) != 1 {
+		return &con.ConflictingDestinationsError {
+			Operation: transform.TransformDescr(),
+			OperationArise: transform.TransformArise(),
+			PathCount: uint(len(destPaths)),
+			PathsAreDestinations: true,
+		}
+	}
+	if plan.StepCount() == stepCount {
+		content, err := render(destPaths[0])
+		if err != nil {
+			return err
+		}
+		if !ContentDiffers(destPaths[0], content) {
+			return nil
+		}
+	}
+	step := &ContentStep {
+		Destination: destPaths[0],
+		Render: func() ([]byte, herr.BuildError) {
+			return render(destPaths[0])
+		},
+		Arise: transform.TransformArise(),
+	}
+	step.Description = fmt.Sprintf(
+		"[%s] %s",
+		destination.ArtifactKey().Project,
+		describe(destPaths[0]),
+	)
+	plan.AddStep(step)
+	return nil
+}
+
+func PlanContentTransform(
+	transform abs.Transform,
+	sources []abs.Artifact,
+	destination abs.Artifact,
+	plan *abs.Plan,
+	render func(dest string) ([]byte, herr.BuildError),
+	describe func(dest string) string,
+) herr.BuildError {
+	err := planContent(transform, sources, destination, plan, render, describe)
+	if err != nil {
+		err.AddErrorFrame(&con.ApplyTransformFrame {
+			Transform: transform,
+		})
+	}
+	return err
+}
+
+type ConcatTransform struct {
+	con.MultiTransformBase
+	Separator string
+	Header string
+	Footer string
+	UIBase string
+}
+
+func NewConcatTransform(arise *herr.AriseRef, uiBase string) *ConcatTransform {
+	transform := &ConcatTransform {
+		UIBase: uiBase,
+	}
+	transform.Description = "concat"
+	transform.Arise = arise
+	return transform
+}
+
+func (xform *ConcatTransform) Render(dest string) ([]byte, herr.BuildError) {
+	paths, err := con.PathsOfArtifacts(xform.Sources)
+	if err != nil {
+		return nil, err
+	}
+	var sink bytes.Buffer
+	sink.WriteString(xform.Header)
+	for index, path := range paths {
+		if index > 0 {
+			sink.WriteString(xform.Separator)
+		}
+		content, oserr := os.ReadFile(path)
+		if oserr != nil {
+			return nil, &ReadFileError {
+				Path: path,
+				OSError: oserr,
+				OperationArise: xform.Arise,
+			}
+		}
+		sink.Write(content)
+	}
+	sink.WriteString(xform.Footer)
+	return sink.Bytes(), nil
+}
+
+func (xform *ConcatTransform) Plan(destination abs.Artifact, plan *abs.Plan) herr.BuildError {
+	return PlanContentTransform(
+		xform,
+		xform.Sources,
+		destination,
+		plan,
+		xform.Render,
+		func(dest string) string {
+			paths, _ := con.PathsOfArtifacts(xform.Sources)
+			return fmt.Sprintf(
+				"concat %s -> %s",
+				filepath.ToSlash(con.RelPath(con.GuessGroupArtifactNameNat(paths, xform.UIBase), xform.UIBase)),
+				filepath.ToSlash(con.RelPath(dest, xform.UIBase)),
+			)
+		},
+	)
+}
+
+func (xform *ConcatTransform) DumpTransform(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Out = os.Stdout
+	prn.Level(level)
+	prn.Println("concat {")
+	for _, source := range xform.Sources {
+		prn.Indent(1)
+		con.PrintErrorString(prn, source.ArtifactKey().Unified())
+		prn.Println()
+	}
+	if len(xform.Separator) > 0 {
+		prn.Indent(1)
+		prn.Print("separator ")
+		con.PrintErrorString(prn, xform.Separator)
+		prn.Println()
+	}
+	if len(xform.Header) > 0 {
+		prn.Indent(1)
+		prn.Print("header ")
+		con.PrintErrorString(prn, xform.Header)
+		prn.Println()
+	}
+	if len(xform.Footer) > 0 {
+		prn.Indent(1)
+		prn.Print("footer ")
+		con.PrintErrorString(prn, xform.Footer)
+		prn.Println()
+	}
+	prn.Indent(0)
+	prn.Print("}")
+	return prn.Done()
+}
+
+var _ abs.Transform = &ConcatTransform{}
+
+type WriteTransform struct {
+	con.TransformBase
+	Content string
+	UIBase string
+}
+
+func NewWriteTransform(arise *herr.AriseRef, content string, uiBase string) *WriteTransform {
+	transform := &WriteTransform {
+		Content: content,
+		UIBase: uiBase,
+	}
+	transform.Description = "write"
+	transform.Arise = arise
+	return transform
+}
+
+func (xform *WriteTransform) Plan(destination abs.Artifact, plan *abs.Plan) herr.BuildError {
+	return PlanContentTransform(
+		xform,
+		nil,
+		destination,
+		plan,
+		func(dest string) ([]byte, herr.BuildError) {
+			return []byte(xform.Content), nil
+		},
+		func(dest string) string {
+			return "write " + filepath.ToSlash(con.RelPath(dest, xform.UIBase))
+		},
+	)
+}
+
+func (xform *WriteTransform) DumpTransform(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Out = os.Stdout
+	prn.Print("write ")
+	con.PrintErrorString(prn, xform.Content)
+	return prn.Done()
+}
+
+var _ abs.Transform = &WriteTransform{}
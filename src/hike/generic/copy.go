@@ -4,8 +4,10 @@ import (
 	"os"
 	"io"
 	"fmt"
+	"regexp"
 	"path/filepath"
 	herr "hike/error"
+	hlv "hike/hilevel"
 	loc "hike/location"
 	abs "hike/abstract"
 	con "hike/concrete"
@@ -51,11 +53,9 @@ var _ herr.BuildError = &FileCopyIOError{}
 
 type CopyStep struct {
 	con.StepBase
+	CopyTransformBase
 	Sources []abs.Artifact
 	Destination abs.Artifact
-	DestinationIsDir bool
-	RebaseFrom string
-	Arise *herr.AriseRef
 }
 
 func (step *CopyStep) Perform() herr.BuildError {
@@ -73,19 +73,15 @@ func (step *CopyStep) Perform() herr.BuildError {
 	}
 	dest := destPaths[0]
 	srcPaths, err := con.PathsOfArtifacts(step.Sources)
+	if err == nil {
+		srcPaths, err = step.selectSources(srcPaths)
+	}
 	if err != nil {
 		return err
 	}
 	if step.DestinationIsDir {
-		var joined string
 		for _, src := range srcPaths {
-			rel := con.ForceToRelativeAndRebase(src, step.RebaseFrom)
-			if filepath.IsAbs(rel) {
-				joined = rel
-			} else {
-				joined = filepath.Join(dest, rel)
-			}
-			err = step.doCopyFile(src, joined)
+			err = step.doCopyFile(src, step.TargetPath(src, dest))
 			if err != nil {
 				return err
 			}
@@ -122,15 +118,67 @@ func (step *CopyStep) doCopyFile(src, dest string) herr.BuildError {
 	if err != nil {
 		return err
 	}
+	if step.PreserveSymlinks {
+		linfo, nerr := os.Lstat(src)
+		if nerr != nil {
+			return step.fileCopyFailed(src, dest, nerr)
+		}
+		if linfo.Mode() & os.ModeSymlink != 0 {
+			target, nerr := os.Readlink(src)
+			if nerr == nil {
+				nerr = ReplaceWithLink(target, dest, false)
+			}
+			if nerr != nil {
+				return step.fileCopyFailed(src, dest, nerr)
+			}
+			return nil
+		}
+	}
 	info, nerr := os.Stat(src)
 	if nerr != nil {
 		return step.fileCopyFailed(src, dest, nerr)
 	}
+	if !step.SkipUnchanged || !SameFileContent(src, dest, info.Size()) {
+		err = step.writeFileCopy(src, dest, info)
+		if err != nil {
+			return err
+		}
+	}
+	if step.PreserveTimes {
+		nerr = os.Chtimes(dest, info.ModTime(), info.ModTime())
+		if nerr != nil {
+			return step.fileCopyFailed(src, dest, nerr)
+		}
+	}
+	return nil
+}
+
+func SameFileContent(src string, dest string, size int64) bool {
+	destInfo, err := os.Lstat(dest)
+	if err != nil || !destInfo.Mode().IsRegular() || destInfo.Size() != size {
+		return false
+	}
+	srcDigest, err := con.FileDigest(src, "sha256")
+	if err != nil {
+		return false
+	}
+	destDigest, err := con.FileDigest(dest, "sha256")
+	return err == nil && srcDigest == destDigest
+}
+
+func (step *CopyStep) writeFileCopy(src string, dest string, info os.FileInfo) herr.BuildError {
 	inf, nerr := os.Open(src)
 	if nerr != nil {
 		return step.fileCopyFailed(src, dest, nerr)
 	}
 	defer inf.Close()
+	destInfo, nerr := os.Lstat(dest)
+	if nerr == nil && destInfo.Mode() & os.ModeSymlink != 0 {
+		nerr = os.Remove(dest)
+		if nerr != nil {
+			return step.fileCopyFailed(src, dest, nerr)
+		}
+	}
 	outf, nerr := os.OpenFile(dest, os.O_WRONLY | os.O_CREATE | os.O_TRUNC, info.Mode() & 0777)
 	if nerr != nil {
 		return step.fileCopyFailed(src, dest, nerr)
@@ -146,14 +194,166 @@ func (step *CopyStep) doCopyFile(src, dest string) herr.BuildError {
 
 var _ abs.Step = &CopyStep{}
 
+type CopyFileStep struct {
+	con.StepBase
+	Source string
+	Destination string
+	PreserveSymlinks bool
+	Arise *herr.AriseRef
+}
+
+func (step *CopyFileStep) Perform() herr.BuildError {
+	copier := &CopyStep{}
+	copier.PreserveSymlinks = step.PreserveSymlinks
+	copier.Arise = step.Arise
+	return copier.doCopyFile(step.Source, step.Destination)
+}
+
+var _ abs.Step = &CopyFileStep{}
+
 // ---------------------------------------- Transform ----------------------------------------
 
 type CopyTransformBase struct {
 	DestinationIsDir bool
 	RebaseFrom string
+	PreserveSymlinks bool
+	PreserveTimes bool
+	SkipUnchanged bool
+	BasenameRegex *regexp.Regexp
+	BasenameRegexText string
+	BasenameReplacement string
+	Filters []hlv.FileFilter
 	Arise *herr.AriseRef
 }
 
+func (base *CopyTransformBase) AddFilter(filter hlv.FileFilter) {
+	base.Filters = append(base.Filters, filter)
+}
+
+func (base *CopyTransformBase) AcceptsSource(src string, info os.FileInfo) bool {
+	for _, filter := range base.Filters {
+		if !filter.AcceptFile(src, base.RebaseFrom, info) {
+			return false
+		}
+	}
+	return true
+}
+
+func (base *CopyTransformBase) selectSources(srcPaths []string) ([]string, herr.BuildError) {
+	if len(base.Filters) == 0 {
+		return srcPaths, nil
+	}
+	for _, filter := range base.Filters {
+		err := filter.PrepareFilter()
+		if err != nil {
+			return nil, err
+		}
+	}
+	var selected []string
+	for _, src := range srcPaths {
+		var info os.FileInfo
+		var nerr error
+		if base.PreserveSymlinks {
+			info, nerr = os.Lstat(src)
+		} else {
+			info, nerr = os.Stat(src)
+		}
+		if nerr != nil {
+			return nil, &con.CannotStatError {
+				Path: src,
+				OSError: nerr,
+				OperationArise: base.Arise,
+			}
+		}
+		if base.AcceptsSource(src, info) {
+			selected = append(selected, src)
+		}
+	}
+	return selected, nil
+}
+
+func (base *CopyTransformBase) TargetPath(src string, dest string) string {
+	if !base.DestinationIsDir {
+		return dest
+	}
+	rel := base.RenameTail(con.ForceToRelativeAndRebase(src, base.RebaseFrom))
+	if filepath.IsAbs(rel) {
+		return rel
+	} else {
+		return filepath.Join(dest, rel)
+	}
+}
+
+func (base *CopyTransformBase) targetIsCurrent(src string, target string) bool {
+	if base.PreserveSymlinks {
+		srcInfo, err := os.Lstat(src)
+		if err != nil {
+			return false
+		}
+		if srcInfo.Mode() & os.ModeSymlink != 0 {
+			srcTarget, serr := os.Readlink(src)
+			destTarget, derr := os.Readlink(target)
+			return serr == nil && derr == nil && srcTarget == destTarget
+		}
+	}
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return false
+	}
+	destInfo, err := os.Lstat(target)
+	if err != nil || !destInfo.Mode().IsRegular() {
+		return false
+	}
+	return destInfo.Size() == srcInfo.Size() && destInfo.ModTime().Equal(srcInfo.ModTime())
+}
+
+func (base *CopyTransformBase) RenameTail(tail string) string {
+	if base.BasenameRegex == nil {
+		return tail
+	}
+	dirname, basename := filepath.Split(tail)
+	if len(basename) == 0 {
+		return tail
+	}
+	return dirname + base.BasenameRegex.ReplaceAllString(basename, base.BasenameReplacement)
+}
+
+func (base *CopyTransformBase) DumpOptions(prn *herr.ErrorPrinter, level uint) {
+	prn.Indent(level)
+	prn.Print("rebaseFrom ")
+	con.PrintErrorString(prn, base.RebaseFrom)
+	prn.Println()
+	if base.DestinationIsDir {
+		prn.Indent(level)
+		prn.Println("toDirectory")
+	}
+	if base.PreserveSymlinks {
+		prn.Indent(level)
+		prn.Println("preserveSymlinks")
+	}
+	if base.PreserveTimes {
+		prn.Indent(level)
+		prn.Println("preserveTimes")
+	}
+	if base.SkipUnchanged {
+		prn.Indent(level)
+		prn.Println("skipUnchanged")
+	}
+	if base.BasenameRegex != nil {
+		prn.Indent(level)
+		prn.Print("rename ")
+		con.PrintErrorString(prn, base.BasenameRegexText)
+		prn.Print(" ")
+		con.PrintErrorString(prn, base.BasenameReplacement)
+		prn.Println()
+	}
+	for _, filter := range base.Filters {
+		prn.Indent(level)
+		prn.Inject(filter.DumpFilter, level)
+		prn.Println()
+	}
+}
+
 type CopyTransform struct {
 	CopyTransformBase
 	Sources []abs.Artifact
@@ -177,44 +377,99 @@ func (xform *CopyTransform) pathDescription(paths []string, base string) string
 	return filepath.ToSlash(con.RelPath(con.GuessGroupArtifactNameNat(paths, base), xform.UIBase))
 }
 
+func (xform *CopyTransform) targetsAreCurrent(destination abs.Artifact) (bool, herr.BuildError) {
+	destPaths, err := destination.PathNames(nil)
+	if err != nil || len(destPaths) != 1 {
+		return false, err
+	}
+	srcPaths, err := con.PathsOfArtifacts(xform.Sources)
+	if err == nil {
+		srcPaths, err = xform.selectSources(srcPaths)
+	}
+	if err != nil {
+		return false, err
+	}
+	if !xform.DestinationIsDir && len(srcPaths) != 1 {
+		return false, nil
+	}
+	for _, src := range srcPaths {
+		if !xform.targetIsCurrent(src, xform.TargetPath(src, destPaths[0])) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (xform *CopyTransform) planPreservingTimes(
+	destination abs.Artifact,
+	plan *abs.Plan,
+	planner func() herr.BuildError,
+) herr.BuildError {
+	stepCount := plan.StepCount()
+	for _, source := range xform.Sources {
+		err := source.Require(plan, xform.Arise)
+		if err != nil {
+			err.AddErrorFrame(&con.ApplyTransformFrame {
+				Transform: xform,
+			})
+			return err
+		}
+	}
+	if plan.StepCount() == stepCount {
+		current, err := xform.targetsAreCurrent(destination)
+		if err != nil {
+			err.AddErrorFrame(&con.ApplyTransformFrame {
+				Transform: xform,
+			})
+			return err
+		}
+		if current {
+			return nil
+		}
+	}
+	return planner()
+}
+
 func (xform *CopyTransform) Plan(destination abs.Artifact, plan *abs.Plan) herr.BuildError {
+	planner := func() herr.BuildError {
+		srcPaths, err := con.PathsOfArtifacts(xform.Sources)
+		if err != nil {
+			err.AddErrorFrame(&con.ApplyTransformFrame {
+				Transform: xform,
+			})
+			return err
+		}
+		destPaths, err := destination.PathNames(nil)
+		if err != nil {
+			err.AddErrorFrame(&con.ApplyTransformFrame {
+				Transform: xform,
+			})
+			return err
+		}
+		step := &CopyStep {
+			CopyTransformBase: xform.CopyTransformBase,
+			Sources: xform.Sources,
+			Destination: destination,
+		}
+		step.Description = fmt.Sprintf(
+			"[%s] copy %s -> %s",
+			destination.ArtifactKey().Project,
+			xform.pathDescription(srcPaths, xform.RebaseFrom),
+			xform.pathDescription(destPaths, xform.UIBase),
+		)
+		plan.AddStep(step)
+		return nil
+	}
+	if xform.PreserveTimes {
+		return xform.planPreservingTimes(destination, plan, planner)
+	}
 	return con.PlanMultiTransform(
 		xform,
 		xform.Sources,
 		destination,
 		plan,
 		con.RequireNoMore,
-		func() herr.BuildError {
-			srcPaths, err := con.PathsOfArtifacts(xform.Sources)
-			if err != nil {
-				err.AddErrorFrame(&con.ApplyTransformFrame {
-					Transform: xform,
-				})
-				return err
-			}
-			destPaths, err := destination.PathNames(nil)
-			if err != nil {
-				err.AddErrorFrame(&con.ApplyTransformFrame {
-					Transform: xform,
-				})
-				return err
-			}
-			step := &CopyStep {
-				Sources: xform.Sources,
-				Destination: destination,
-				DestinationIsDir: xform.DestinationIsDir,
-				RebaseFrom: xform.RebaseFrom,
-				Arise: xform.Arise,
-			}
-			step.Description = fmt.Sprintf(
-				"[%s] copy %s -> %s",
-				destination.ArtifactKey().Project,
-				xform.pathDescription(srcPaths, xform.RebaseFrom),
-				xform.pathDescription(destPaths, xform.UIBase),
-			)
-			plan.AddStep(step)
-			return nil
-		},
+		planner,
 	)
 }
 
@@ -228,14 +483,7 @@ func (xform *CopyTransform) DumpTransform(level uint) error {
 		con.PrintErrorString(prn, source.ArtifactKey().Unified())
 		prn.Println()
 	}
-	prn.Indent(1)
-	prn.Print("rebaseFrom ")
-	con.PrintErrorString(prn, xform.RebaseFrom)
-	prn.Println()
-	if xform.DestinationIsDir {
-		prn.Indent(1)
-		prn.Println("toDirectory")
-	}
+	xform.DumpOptions(prn, 1)
 	prn.Indent(0)
 	prn.Print("}")
 	return prn.Done()
@@ -1,9 +1,11 @@
 package generic
 
 import (
+	"io"
 	"os"
 	"fmt"
 	"bufio"
+	"bytes"
 	"os/exec"
 	"strings"
 	herr "hike/error"
@@ -82,8 +84,177 @@ type VariableCommandLine func([]string, []string) ([][]string, herr.BuildError)
 type CommandLineDumper func(uint) error
 type CommandWordsRequirer func(*abs.Plan, *herr.AriseRef) herr.BuildError
 
+type CommandStreams struct {
+	StdoutToDest bool
+	StdinFromSource bool
+	StderrTo string
+}
+
+func (streams *CommandStreams) runCommand(
+	argv []string,
+	stdinPath string,
+	stdout io.Writer,
+	stderr io.Writer,
+	loud bool,
+	arise *herr.AriseRef,
+) herr.BuildError {
+	cmd := exec.Command(argv[0])
+	cmd.Args = argv
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if len(stdinPath) > 0 {
+		file, err := os.Open(stdinPath)
+		if err != nil {
+			return &ReadFileError {
+				Path: stdinPath,
+				OSError: err,
+				OperationArise: arise,
+			}
+		}
+		defer file.Close()
+		cmd.Stdin = file
+	}
+	if stdout != nil {
+		cmd.Stdout = stdout
+	}
+	if stderr != nil {
+		cmd.Stderr = io.MultiWriter(stderr, &output)
+	}
+	err := cmd.Run()
+	if err != nil {
+		return &CommandFailedError {
+			Argv: argv,
+			Fault: err,
+			Output: output.Bytes(),
+			ExecArise: arise,
+		}
+	}
+	if loud {
+		fmt.Print(output.String())
+	}
+	return nil
+}
+
+func (streams *CommandStreams) RunCommands(
+	argvs [][]string,
+	srcPaths []string,
+	destPaths []string,
+	loud bool,
+	arise *herr.AriseRef,
+) herr.BuildError {
+	var stdinPath, stdoutPath string
+	var err herr.BuildError
+	if streams.StdinFromSource {
+		stdinPath, err = con.SinglePath(srcPaths, "feed standard input of command", false, arise)
+		if err != nil {
+			return err
+		}
+	}
+	if streams.StdoutToDest {
+		stdoutPath, err = con.SinglePath(destPaths, "capture standard output of command", true, arise)
+		if err != nil {
+			return err
+		}
+	}
+	var stdout, stderr *os.File
+	if len(stdoutPath) > 0 {
+		var oserr error
+		stdout, oserr = os.Create(stdoutPath)
+		if oserr != nil {
+			return &WriteFileError {
+				Path: stdoutPath,
+				OSError: oserr,
+				OperationArise: arise,
+			}
+		}
+		defer stdout.Close()
+	}
+	if len(streams.StderrTo) > 0 {
+		err = con.MakeEnclosingDirectories(streams.StderrTo, arise)
+		if err != nil {
+			return err
+		}
+		var oserr error
+		stderr, oserr = os.Create(streams.StderrTo)
+		if oserr != nil {
+			return &WriteFileError {
+				Path: streams.StderrTo,
+				OSError: oserr,
+				OperationArise: arise,
+			}
+		}
+		defer stderr.Close()
+	}
+	for _, argv := range argvs {
+		if len(argv) == 0 {
+			continue
+		}
+		var stdoutSink, stderrSink io.Writer
+		if stdout != nil {
+			stdoutSink = stdout
+		}
+		if stderr != nil {
+			stderrSink = stderr
+		}
+		err = streams.runCommand(argv, stdinPath, stdoutSink, stderrSink, loud, arise)
+		if err != nil {
+			if stdout != nil {
+				stdout.Close()
+				os.Remove(stdoutPath)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (streams *CommandStreams) CheckStreams(
+	sources []abs.Artifact,
+	destination abs.Artifact,
+	arise *herr.AriseRef,
+) herr.BuildError {
+	if streams.StdinFromSource {
+		srcPaths, err := con.PathsOfArtifacts(sources)
+		if err == nil {
+			_, err = con.SinglePath(srcPaths, "feed standard input of command", false, arise)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if streams.StdoutToDest && destination != nil {
+		destPaths, err := destination.PathNames(nil)
+		if err == nil {
+			_, err = con.SinglePath(destPaths, "capture standard output of command", true, arise)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (streams *CommandStreams) DumpStreams(prn *herr.ErrorPrinter) {
+	if streams.StdoutToDest {
+		prn.Indent(1)
+		prn.Println("stdoutToDest")
+	}
+	if streams.StdinFromSource {
+		prn.Indent(1)
+		prn.Println("stdinFromSource")
+	}
+	if len(streams.StderrTo) > 0 {
+		prn.Indent(1)
+		prn.Print("stderrTo ")
+		con.PrintErrorString(prn, streams.StderrTo)
+		prn.Println()
+	}
+}
+
 type CommandStep struct {
 	con.StepBase
+	CommandStreams
 	Sources []abs.Artifact
 	Destination abs.Artifact
 	CommandLine VariableCommandLine
@@ -110,32 +281,14 @@ func (step *CommandStep) Perform() herr.BuildError {
 	if err != nil {
 		return err
 	}
-	for _, argv := range argvs {
-		if len(argv) == 0 {
-			continue
-		}
-		cmd := exec.Command(argv[0])
-		cmd.Args = argv
-		out, err := cmd.CombinedOutput()
-		if err != nil {
-			return &CommandFailedError {
-				Argv: argv,
-				Fault: err,
-				Output: out,
-				ExecArise: step.CommandArise,
-			}
-		}
-		if step.Loud {
-			fmt.Print(string(out))
-		}
-	}
-	return nil
+	return step.RunCommands(argvs, srcPaths, destPaths, step.Loud, step.CommandArise)
 }
 
 var _ abs.Step = &CommandStep{}
 
 type StandAloneCommandStep struct {
 	con.StepBase
+	CommandStreams
 	Sources []abs.Artifact
 	CommandLine VariableCommandLine
 	Loud bool
@@ -151,26 +304,7 @@ func (step *StandAloneCommandStep) Perform() herr.BuildError {
 	if err != nil {
 		return err
 	}
-	for _, argv := range argvs {
-		if len(argv) == 0 {
-			continue
-		}
-		cmd := exec.Command(argv[0])
-		cmd.Args = argv
-		out, err := cmd.CombinedOutput()
-		if err != nil {
-			return &CommandFailedError {
-				Argv: argv,
-				Fault: err,
-				Output: out,
-				ExecArise: step.CommandArise,
-			}
-		}
-		if step.Loud {
-			fmt.Print(string(out))
-		}
-	}
-	return nil
+	return step.RunCommands(argvs, srcPaths, nil, step.Loud, step.CommandArise)
 }
 
 var _ abs.Step = &StandAloneCommandStep{}
@@ -250,6 +384,7 @@ var _ abs.Step = &MkdirStep{}
 // ---------------------------------------- Transform ----------------------------------------
 
 type CommandTransformBase struct {
+	CommandStreams
 	CommandLine VariableCommandLine
 	DumpCommandLine CommandLineDumper
 	RequireCommandWords CommandWordsRequirer
@@ -263,7 +398,11 @@ func (base *CommandTransformBase) PlanCommandTransform(
 	destination abs.Artifact,
 	plan *abs.Plan,
 	transformArise *herr.AriseRef,
-) {
+) herr.BuildError {
+	err := base.CheckStreams(sources, destination, transformArise)
+	if err != nil {
+		return err
+	}
 	step := &CommandStep {
 		Sources: sources,
 		Destination: destination,
@@ -271,6 +410,7 @@ func (base *CommandTransformBase) PlanCommandTransform(
 		Loud: base.Loud,
 		CommandArise: transformArise,
 	}
+	step.CommandStreams = base.CommandStreams
 	var suffix string
 	if base.SuffixIsDestination || len(sources) != 1 {
 		suffix = destination.DisplayName()
@@ -279,6 +419,7 @@ func (base *CommandTransformBase) PlanCommandTransform(
 	}
 	step.Description = fmt.Sprintf("[%s] %s %s", destination.ArtifactKey().Project, descriptionPrefix, suffix)
 	plan.AddStep(step)
+	return nil
 }
 
 func (base *CommandTransformBase) CommandWordsRequirer(plan *abs.Plan, arise *herr.AriseRef) func() herr.BuildError {
@@ -300,14 +441,13 @@ func (transform *SingleCommandTransform) Plan(destination abs.Artifact, plan *ab
 		plan,
 		transform.CommandWordsRequirer(plan, transform.Arise),
 		func() herr.BuildError {
-			transform.PlanCommandTransform(
+			return transform.PlanCommandTransform(
 				transform.Description,
 				[]abs.Artifact{transform.Source},
 				destination,
 				plan,
 				transform.TransformArise(),
 			)
-			return nil
 		},
 	)
 }
@@ -333,6 +473,7 @@ func (transform *SingleCommandTransform) DumpTransform(level uint) error {
 		prn.Indent(1)
 		prn.Println("suffixIsDestination")
 	}
+	transform.DumpStreams(prn)
 	prn.Indent(1)
 	prn.Print("artifact ")
 	con.PrintErrorString(prn, transform.Source.ArtifactKey().Unified())
@@ -379,14 +520,13 @@ func (transform *MultiCommandTransform) Plan(destination abs.Artifact, plan *abs
 		plan,
 		transform.CommandWordsRequirer(plan, transform.Arise),
 		func() herr.BuildError {
-			transform.PlanCommandTransform(
+			return transform.PlanCommandTransform(
 				transform.Description,
 				transform.Sources,
 				destination,
 				plan,
 				transform.TransformArise(),
 			)
-			return nil
 		},
 	)
 }
@@ -412,6 +552,7 @@ func (transform *MultiCommandTransform) DumpTransform(level uint) error {
 		prn.Indent(1)
 		prn.Println("suffixIsDestination")
 	}
+	transform.DumpStreams(prn)
 	for _, source := range transform.Sources {
 		prn.Indent(1)
 		prn.Print("artifact ")
@@ -539,6 +680,7 @@ var _ abs.Action = &DeleteArtifactAction{}
 
 type CommandAction struct {
 	con.ActionBase
+	CommandStreams
 	Sources []abs.Artifact
 	Description string
 	Project string
@@ -563,6 +705,9 @@ func (action *CommandAction) Perform(plan *abs.Plan) herr.BuildError {
 		}
 	}
 	err := action.RequireCommandWords(plan, action.Arise)
+	if err == nil {
+		err = action.CheckStreams(action.Sources, nil, action.Arise)
+	}
 	if err != nil {
 		return err
 	}
@@ -572,6 +717,7 @@ func (action *CommandAction) Perform(plan *abs.Plan) herr.BuildError {
 		Loud: action.Loud,
 		CommandArise: action.Arise,
 	}
+	step.CommandStreams = action.CommandStreams
 	step.Description = fmt.Sprintf("[%s] %s", action.Project, action.Description)
 	plan.AddStep(step)
 	return nil
@@ -0,0 +1,208 @@
+package generic
+
+import (
+	"os"
+	"fmt"
+	"path/filepath"
+	herr "hike/error"
+	loc "hike/location"
+	abs "hike/abstract"
+	con "hike/concrete"
+)
+
+// ---------------------------------------- BuildError ----------------------------------------
+
+type CreateLinkError struct {
+	herr.BuildErrorBase
+	Target string
+	Destination string
+	OSError error
+	OperationArise *herr.AriseRef
+}
+
+func (create *CreateLinkError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Println("Failed to create link")
+	prn.Indent(1)
+	prn.Println(create.Destination)
+	prn.Indent(0)
+	prn.Println("pointing to")
+	prn.Indent(1)
+	prn.Println(create.Target)
+	prn.Indent(0)
+	prn.Print("in operation ")
+	prn.Arise(create.OperationArise, 0)
+	prn.Println()
+	prn.Indent(0)
+	prn.Printf("because: %s", create.OSError.Error())
+	create.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (create *CreateLinkError) BuildErrorLocation() *loc.Location {
+	return create.OperationArise.Location
+}
+
+var _ herr.BuildError = &CreateLinkError{}
+
+// ---------------------------------------- Step ----------------------------------------
+
+func SymlinkTargetText(target string, dest string, relative bool) (string, error) {
+	if !relative || !filepath.IsAbs(target) {
+		return target, nil
+	}
+	return filepath.Rel(filepath.Dir(dest), target)
+}
+
+func ReplaceWithLink(target string, dest string, hard bool) error {
+	info, err := os.Lstat(dest)
+	switch {
+		case err == nil && info.IsDir():
+			return fmt.Errorf("destination is a directory")
+		case err == nil:
+			err = os.Remove(dest)
+			if err != nil {
+				return err
+			}
+		case !os.IsNotExist(err):
+			return err
+	}
+	if hard {
+		return os.Link(target, dest)
+	} else {
+		return os.Symlink(target, dest)
+	}
+}
+
+type LinkStep struct {
+	con.StepBase
+	Target string
+	Destination string
+	Hard bool
+	Relative bool
+	Arise *herr.AriseRef
+}
+
+func (step *LinkStep) Perform() herr.BuildError {
+	err := con.MakeEnclosingDirectories(step.Destination, step.Arise)
+	if err != nil {
+		return err
+	}
+	target := step.Target
+	var nerr error
+	if !step.Hard {
+		target, nerr = SymlinkTargetText(step.Target, step.Destination, step.Relative)
+	}
+	if nerr == nil {
+		nerr = ReplaceWithLink(target, step.Destination, step.Hard)
+	}
+	if nerr != nil {
+		return &CreateLinkError {
+			Target: target,
+			Destination: step.Destination,
+			OSError: nerr,
+			OperationArise: step.Arise,
+		}
+	}
+	return nil
+}
+
+var _ abs.Step = &LinkStep{}
+
+// ---------------------------------------- Transform ----------------------------------------
+
+type LinkTransform struct {
+	con.SingleTransformBase
+	Hard bool
+	Relative bool
+	UIBase string
+}
+
+func NewLinkTransform(
+	arise *herr.AriseRef,
+	source abs.Artifact,
+	hard bool,
+	relative bool,
+	uiBase string,
+) *LinkTransform {
+	transform := &LinkTransform {
+		Hard: hard,
+		Relative: relative,
+		UIBase: uiBase,
+	}
+	transform.Description = "link"
+	transform.Arise = arise
+	transform.Source = source
+	return transform
+}
+
+func (xform *LinkTransform) singlePath(artifact abs.Artifact, isDest bool) (string, herr.BuildError) {
+	paths, err := artifact.PathNames(nil)
+	if err != nil {
+		return "", err
+	}
+	return con.SinglePath(paths, "link " + xform.Source.DisplayName(), isDest, xform.Arise)
+}
+
+func (xform *LinkTransform) Plan(destination abs.Artifact, plan *abs.Plan) herr.BuildError {
+	return con.PlanSingleTransform(
+		xform,
+		xform.Source,
+		destination,
+		plan,
+		con.RequireNoMore,
+		func() herr.BuildError {
+			src, err := xform.singlePath(xform.Source, false)
+			if err != nil {
+				return err
+			}
+			dest, err := xform.singlePath(destination, true)
+			if err != nil {
+				return err
+			}
+			step := &LinkStep {
+				Target: src,
+				Destination: dest,
+				Hard: xform.Hard,
+				Relative: xform.Relative,
+				Arise: xform.Arise,
+			}
+			step.Description = fmt.Sprintf(
+				"[%s] link %s -> %s",
+				destination.ArtifactKey().Project,
+				filepath.ToSlash(con.RelPath(dest, xform.UIBase)),
+				filepath.ToSlash(con.RelPath(src, xform.UIBase)),
+			)
+			plan.AddStep(step)
+			return nil
+		},
+	)
+}
+
+func (xform *LinkTransform) DumpTransform(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Out = os.Stdout
+	prn.Level(level)
+	prn.Println("link {")
+	prn.Indent(1)
+	con.PrintErrorString(prn, xform.Source.ArtifactKey().Unified())
+	prn.Println()
+	prn.Indent(1)
+	if xform.Hard {
+		prn.Println("hard")
+	} else {
+		prn.Println("symbolic")
+		prn.Indent(1)
+		if xform.Relative {
+			prn.Println("relative")
+		} else {
+			prn.Println("absolute")
+		}
+	}
+	prn.Indent(0)
+	prn.Print("}")
+	return prn.Done()
+}
+
+var _ abs.Transform = &LinkTransform{}
@@ -0,0 +1,398 @@
+package generic
+
+import (
+	"os"
+	"io"
+	"fmt"
+	"path"
+	"time"
+	"archive/tar"
+	"compress/gzip"
+	herr "hike/error"
+	loc "hike/location"
+	abs "hike/abstract"
+	con "hike/concrete"
+)
+
+// ---------------------------------------- BuildError ----------------------------------------
+
+type CreateTarError struct {
+	herr.BuildErrorBase
+	Destination string
+	LibError error
+	OperationArise *herr.AriseRef
+}
+
+func (create *CreateTarError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Println("Failed to create tar archive")
+	prn.Indent(1)
+	prn.Println(create.Destination)
+	prn.Indent(0)
+	prn.Print("in operation ")
+	prn.Arise(create.OperationArise, 0)
+	prn.Println()
+	prn.Indent(0)
+	prn.Printf("because: %s", create.LibError.Error())
+	create.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (create *CreateTarError) BuildErrorLocation() *loc.Location {
+	return create.OperationArise.Location
+}
+
+var _ herr.BuildError = &CreateTarError{}
+
+// ---------------------------------------- Step ----------------------------------------
+
+const TAR_OWNER_NAME = "root"
+
+type TarEmitter struct {
+	archive string
+	outf *os.File
+	gzw *gzip.Writer
+	tw *tar.Writer
+	knownDirs map[string]bool
+	firstError herr.BuildError
+}
+
+func NewTarEmitter(archive string, compress bool) (*TarEmitter, error) {
+	outf, nerr := os.OpenFile(archive, os.O_WRONLY | os.O_CREATE | os.O_TRUNC, 0644)
+	if nerr != nil {
+		return nil, nerr
+	}
+	emitter := &TarEmitter {
+		archive: archive,
+		outf: outf,
+		knownDirs: make(map[string]bool),
+	}
+	if compress {
+		emitter.gzw = gzip.NewWriter(outf)
+		emitter.tw = tar.NewWriter(emitter.gzw)
+	} else {
+		emitter.tw = tar.NewWriter(outf)
+	}
+	return emitter, nil
+}
+
+func (emitter *TarEmitter) writeHeader(header *tar.Header) error {
+	header.Uid = 0
+	header.Gid = 0
+	header.Uname = TAR_OWNER_NAME
+	header.Gname = TAR_OWNER_NAME
+	return emitter.tw.WriteHeader(header)
+}
+
+func (emitter *TarEmitter) ensureDirectory(filePath string, modTime time.Time) error {
+	parent := path.Dir(filePath)
+	if parent == "." {
+		return nil
+	}
+	err := emitter.ensureDirectory(parent, modTime)
+	if err != nil {
+		return err
+	}
+	if emitter.knownDirs[parent] {
+		return nil
+	}
+	err = emitter.writeHeader(&tar.Header {
+		Typeflag: tar.TypeDir,
+		Name: parent + "/",
+		Mode: 0755,
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+	emitter.knownDirs[parent] = true
+	return nil
+}
+
+func (emitter *TarEmitter) entryName(name string, info os.FileInfo, fail func(error) herr.BuildError) string {
+	if emitter.firstError != nil {
+		return ""
+	}
+	dest := path.Clean("/" + name)[1:]
+	if len(dest) == 0 {
+		return ""
+	}
+	nerr := emitter.ensureDirectory(dest, info.ModTime())
+	if nerr != nil {
+		emitter.firstError = fail(nerr)
+		return ""
+	}
+	return dest
+}
+
+func (emitter *TarEmitter) EmitFile(
+	name string,
+	info os.FileInfo,
+	content func(io.Writer) herr.BuildError,
+	fail func(error) herr.BuildError,
+) {
+	dest := emitter.entryName(name, info, fail)
+	if len(dest) == 0 {
+		return
+	}
+	nerr := emitter.writeHeader(&tar.Header {
+		Typeflag: tar.TypeReg,
+		Name: dest,
+		Mode: int64(info.Mode().Perm()),
+		Size: info.Size(),
+		ModTime: info.ModTime(),
+	})
+	if nerr != nil {
+		emitter.firstError = fail(nerr)
+		return
+	}
+	err := content(emitter.tw)
+	if err != nil {
+		emitter.firstError = err
+		return
+	}
+}
+
+func (emitter *TarEmitter) EmitDirectory(name string, info os.FileInfo, fail func(error) herr.BuildError) {
+	dest := emitter.entryName(name, info, fail)
+	if len(dest) == 0 || emitter.knownDirs[dest] {
+		return
+	}
+	nerr := emitter.writeHeader(&tar.Header {
+		Typeflag: tar.TypeDir,
+		Name: dest + "/",
+		Mode: int64(info.Mode().Perm()),
+		ModTime: info.ModTime(),
+	})
+	if nerr != nil {
+		emitter.firstError = fail(nerr)
+		return
+	}
+	emitter.knownDirs[dest] = true
+}
+
+func (emitter *TarEmitter) EmitSymlink(name string, target string, info os.FileInfo, fail func(error) herr.BuildError) {
+	dest := emitter.entryName(name, info, fail)
+	if len(dest) == 0 {
+		return
+	}
+	nerr := emitter.writeHeader(&tar.Header {
+		Typeflag: tar.TypeSymlink,
+		Name: dest,
+		Linkname: target,
+		Mode: 0777,
+		ModTime: info.ModTime(),
+	})
+	if nerr != nil {
+		emitter.firstError = fail(nerr)
+	}
+}
+
+func (emitter *TarEmitter) closeAll() error {
+	nerr := emitter.tw.Close()
+	if emitter.gzw != nil {
+		gzerr := emitter.gzw.Close()
+		if nerr == nil {
+			nerr = gzerr
+		}
+	}
+	oserr := emitter.outf.Close()
+	if nerr == nil {
+		nerr = oserr
+	}
+	return nerr
+}
+
+func (emitter *TarEmitter) Finish(fail func(error) herr.BuildError) herr.BuildError {
+	if emitter.firstError != nil {
+		emitter.Die()
+		return emitter.firstError
+	}
+	nerr := emitter.closeAll()
+	if nerr != nil {
+		os.Remove(emitter.archive)
+		return fail(nerr)
+	}
+	return nil
+}
+
+func (emitter *TarEmitter) Die() {
+	emitter.closeAll()
+	os.Remove(emitter.archive)
+}
+
+type TarStep struct {
+	con.StepBase
+	Pieces []*ZipPiece
+	Compress bool
+	Destination abs.Artifact
+	Arise *herr.AriseRef
+}
+
+func (step *TarStep) fail(dest string, err error) herr.BuildError {
+	return &CreateTarError {
+		Destination: dest,
+		LibError: err,
+		OperationArise: step.Arise,
+	}
+}
+
+func (step *TarStep) emitSource(emitter *TarEmitter, dest string, src string, name string) {
+	fail := func(oserr error) herr.BuildError {
+		return step.fail(dest, oserr)
+	}
+	info, oserr := os.Lstat(src)
+	if oserr != nil {
+		emitter.firstError = fail(oserr)
+		return
+	}
+	switch {
+		case info.Mode() & os.ModeSymlink != 0:
+			target, oserr := os.Readlink(src)
+			if oserr != nil {
+				emitter.firstError = fail(oserr)
+				return
+			}
+			emitter.EmitSymlink(name, target, info, fail)
+		case info.IsDir():
+			emitter.EmitDirectory(name, info, fail)
+		default:
+			emitter.EmitFile(
+				name,
+				info,
+				func(into io.Writer) herr.BuildError {
+					inf, oserr := os.Open(src)
+					if oserr != nil {
+						return fail(oserr)
+					}
+					defer inf.Close()
+					_, oserr = io.CopyN(into, inf, info.Size())
+					if oserr != nil {
+						return fail(oserr)
+					}
+					return nil
+				},
+				fail,
+			)
+	}
+}
+
+func (step *TarStep) Perform() herr.BuildError {
+	destPaths, err := step.Destination.PathNames(nil)
+	if err != nil {
+		return err
+	}
+	if len(destPaths) != 1 {
+		return &con.ConflictingDestinationsError {
+			Operation: "create tar archive: " + step.Destination.DisplayName(),
+			OperationArise: step.Arise,
+			PathCount: uint(len(destPaths)),
+			PathsAreDestinations: true,
+		}
+	}
+	dest := destPaths[0]
+	err = con.MakeEnclosingDirectories(dest, step.Arise)
+	if err != nil {
+		return err
+	}
+	emitter, nerr := NewTarEmitter(dest, step.Compress)
+	if nerr != nil {
+		return step.fail(dest, nerr)
+	}
+	for _, piece := range step.Pieces {
+		srcPaths, err := con.PathsOfArtifacts(piece.Sources)
+		if err != nil {
+			emitter.Die()
+			return err
+		}
+		for _, src := range srcPaths {
+			step.emitSource(emitter, dest, src, piece.EntryName(src))
+		}
+	}
+	return emitter.Finish(func(oserr error) herr.BuildError {
+		return step.fail(dest, oserr)
+	})
+}
+
+var _ abs.Step = &TarStep{}
+
+// ---------------------------------------- Transform ----------------------------------------
+
+type TarTransform struct {
+	con.TransformBase
+	Pieces []*ZipPiece
+	Compress bool
+}
+
+func NewTarTransform(description string, arise *herr.AriseRef, pieces []*ZipPiece, compress bool) *TarTransform {
+	transform := &TarTransform {
+		Pieces: pieces,
+		Compress: compress,
+	}
+	transform.Description = description
+	transform.Arise = arise
+	return transform
+}
+
+func (xform *TarTransform) AddPiece(piece *ZipPiece) {
+	xform.Pieces = append(xform.Pieces, piece)
+}
+
+func (xform *TarTransform) Plan(destination abs.Artifact, plan *abs.Plan) herr.BuildError {
+	var sources []abs.Artifact
+	for _, piece := range xform.Pieces {
+		for _, source := range piece.Sources {
+			sources = append(sources, source)
+		}
+	}
+	return con.PlanMultiTransform(
+		xform,
+		sources,
+		destination,
+		plan,
+		con.RequireNoMore,
+		func() herr.BuildError {
+			step := &TarStep {
+				Pieces: xform.Pieces,
+				Compress: xform.Compress,
+				Destination: destination,
+				Arise: xform.Arise,
+			}
+			step.Description = fmt.Sprintf(
+				"[%s] %s %s",
+				destination.ArtifactKey().Project,
+				xform.Description,
+				destination.DisplayName(),
+			)
+			plan.AddStep(step)
+			return nil
+		},
+	)
+}
+
+func (xform *TarTransform) DumpTransform(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Out = os.Stdout
+	prn.Level(level)
+	prn.Print("tar ")
+	con.PrintErrorString(prn, xform.Description)
+	prn.Print(" {")
+	if xform.Compress {
+		prn.Println()
+		prn.Indent(1)
+		prn.Print("gzip")
+	}
+	for _, piece := range xform.Pieces {
+		prn.Println()
+		piece.DumpPiece(prn, 1)
+	}
+	if xform.Compress || len(xform.Pieces) > 0 {
+		prn.Println()
+		prn.Indent(0)
+	}
+	prn.Print("}")
+	return prn.Done()
+}
+
+var _ abs.Transform = &TarTransform{}
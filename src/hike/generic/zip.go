@@ -4,10 +4,14 @@ import (
 	"os"
 	"io"
 	"fmt"
+	"sort"
 	"path"
+	"time"
 	"regexp"
+	"strconv"
 	"archive/zip"
 	"path/filepath"
+	"compress/flate"
 	herr "hike/error"
 	loc "hike/location"
 	abs "hike/abstract"
@@ -45,17 +49,135 @@ func (create *CreateZipError) BuildErrorLocation() *loc.Location {
 
 var _ herr.BuildError = &CreateZipError{}
 
+type IllegalTimestampError struct {
+	herr.BuildErrorBase
+	Specifier string
+	LibError error
+	Location *loc.Location
+}
+
+func (illegal *IllegalTimestampError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Printf("Illegal timestamp '%s':\n", illegal.Specifier)
+	prn.Indent(1)
+	prn.Println(illegal.LibError.Error())
+	prn.Indent(0)
+	prn.Print("at ")
+	prn.Location(illegal.Location)
+	illegal.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (illegal *IllegalTimestampError) BuildErrorLocation() *loc.Location {
+	return illegal.Location
+}
+
+var _ herr.BuildError = &IllegalTimestampError{}
+
+type UnknownCompressionMethodError struct {
+	herr.BuildErrorBase
+	Method string
+	Location *loc.Location
+}
+
+func (unknown *UnknownCompressionMethodError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Printf("Unknown compression method '%s' (expected 'store' or 'deflate')", unknown.Method)
+	prn.Println()
+	prn.Indent(0)
+	prn.Print("at ")
+	prn.Location(unknown.Location)
+	unknown.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (unknown *UnknownCompressionMethodError) BuildErrorLocation() *loc.Location {
+	return unknown.Location
+}
+
+var _ herr.BuildError = &UnknownCompressionMethodError{}
+
 // ---------------------------------------- Step ----------------------------------------
 
+const SOURCE_DATE_EPOCH_VAR = "SOURCE_DATE_EPOCH"
+const ZIP_DEFAULT_LEVEL = flate.DefaultCompression
+
+var ZipEpoch = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func ParseTimestamp(spec string) (time.Time, error) {
+	seconds, err := strconv.ParseInt(spec, 10, 64)
+	if err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	stamp, err := time.Parse(time.RFC3339, spec)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return stamp.UTC(), nil
+}
+
+func ZipCompressionMethod(name string) (uint16, bool) {
+	switch name {
+		case "store":
+			return zip.Store, true
+		case "deflate":
+			return zip.Deflate, true
+		default:
+			return 0, false
+	}
+}
+
+type ZipOptions struct {
+	FixedTime bool
+	Timestamp time.Time
+	TimestampText string
+	Sorted bool
+	PreserveModes bool
+	SkipDirectories bool
+}
+
+func (options *ZipOptions) EntryTime() (time.Time, error) {
+	switch {
+		case !options.FixedTime:
+			return time.Now(), nil
+		case len(options.TimestampText) > 0:
+			return options.Timestamp, nil
+	}
+	epoch := os.Getenv(SOURCE_DATE_EPOCH_VAR)
+	if len(epoch) == 0 {
+		return ZipEpoch, nil
+	}
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("illegal %s value '%s': %s", SOURCE_DATE_EPOCH_VAR, epoch, err.Error())
+	}
+	stamp := time.Unix(seconds, 0).UTC()
+	if stamp.Before(ZipEpoch) {
+		stamp = ZipEpoch
+	}
+	return stamp, nil
+}
+
 type ZipEmitter struct {
 	archive string
 	outf *os.File
 	zw *zip.Writer
 	knownDirs map[string]bool
 	firstError herr.BuildError
+	modTime time.Time
+	fixedTime bool
+	preserveModes bool
+	skipDirectories bool
+	level int
 }
 
-func NewZipEmitter(archive string) (*ZipEmitter, error) {
+func NewZipEmitter(archive string, options *ZipOptions) (*ZipEmitter, error) {
+	modTime, err := options.EntryTime()
+	if err != nil {
+		return nil, err
+	}
 	outf, nerr := os.OpenFile(archive, os.O_WRONLY | os.O_CREATE | os.O_TRUNC, 0644)
 	if nerr != nil {
 		return nil, nerr
@@ -65,7 +187,15 @@ func NewZipEmitter(archive string) (*ZipEmitter, error) {
 		outf: outf,
 		zw: zip.NewWriter(outf),
 		knownDirs: make(map[string]bool),
+		modTime: modTime,
+		fixedTime: options.FixedTime,
+		preserveModes: options.PreserveModes,
+		skipDirectories: options.SkipDirectories,
+		level: ZIP_DEFAULT_LEVEL,
 	}
+	emitter.zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, emitter.level)
+	})
 	return emitter, nil
 }
 
@@ -81,9 +211,19 @@ func (emitter *ZipEmitter) ensureDirectory(filePath string) error {
 	if emitter.knownDirs[parent] {
 		return nil
 	}
-	_, err = emitter.zw.Create(parent + "/")
-	if err != nil {
-		return err
+	if !emitter.skipDirectories {
+		header := &zip.FileHeader {
+			Name: parent + "/",
+			Method: zip.Deflate,
+			Modified: emitter.modTime,
+		}
+		if emitter.preserveModes {
+			header.SetMode(os.ModeDir | 0755)
+		}
+		_, err = emitter.zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
 	}
 	emitter.knownDirs[parent] = true
 	return nil
@@ -91,6 +231,9 @@ func (emitter *ZipEmitter) ensureDirectory(filePath string) error {
 
 func (emitter *ZipEmitter) EmitFile(
 	name string,
+	info os.FileInfo,
+	method uint16,
+	level int,
 	content func(io.Writer) herr.BuildError,
 	fail func(error) herr.BuildError,
 ) {
@@ -106,7 +249,19 @@ func (emitter *ZipEmitter) EmitFile(
 		emitter.firstError = fail(nerr)
 		return
 	}
-	into, nerr := emitter.zw.Create(dest)
+	header := &zip.FileHeader {
+		Name: dest,
+		Method: method,
+		Modified: emitter.modTime,
+	}
+	if !emitter.fixedTime {
+		header.Modified = info.ModTime()
+	}
+	if emitter.preserveModes {
+		header.SetMode(info.Mode().Perm())
+	}
+	emitter.level = level
+	into, nerr := emitter.zw.CreateHeader(header)
 	if nerr != nil {
 		emitter.firstError = fail(nerr)
 		return
@@ -148,10 +303,17 @@ func (emitter *ZipEmitter) Die() {
 type ZipStep struct {
 	con.StepBase
 	Pieces []*ZipPiece
+	Options ZipOptions
 	Destination abs.Artifact
 	Arise *herr.AriseRef
 }
 
+type zipEntry struct {
+	name string
+	source string
+	piece *ZipPiece
+}
+
 func (step *ZipStep) fail(dest string, err error) herr.BuildError {
 	return &CreateZipError {
 		Destination: dest,
@@ -178,10 +340,11 @@ func(step *ZipStep) Perform() herr.BuildError {
 	if err != nil {
 		return err
 	}
-	emitter, nerr := NewZipEmitter(dest)
+	emitter, nerr := NewZipEmitter(dest, &step.Options)
 	if nerr != nil {
 		return step.fail(dest, nerr)
 	}
+	var entries []*zipEntry
 	for _, piece := range step.Pieces {
 		srcPaths, err := con.PathsOfArtifacts(piece.Sources)
 		if err != nil {
@@ -189,33 +352,46 @@ func(step *ZipStep) Perform() herr.BuildError {
 			return err
 		}
 		for _, src := range srcPaths {
-			srcTail := filepath.ToSlash(con.ForceToRelativeAndRebase(src, piece.RebaseFrom))
-			destTail := filepath.ToSlash(piece.RebaseTo) + path.Clean("/" + srcTail)
-			if piece.BasenameRegex != nil {
-				destDirname, destBasename := path.Split(path.Clean(destTail))
-				if len(destBasename) > 0 {
-					newTail := piece.BasenameRegex.ReplaceAllString(destBasename, piece.BasenameReplacement)
-					destTail = destDirname + newTail
+			entries = append(entries, &zipEntry {
+				name: piece.EntryName(src),
+				source: src,
+				piece: piece,
+			})
+		}
+	}
+	if step.Options.Sorted {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].name < entries[j].name
+		})
+	}
+	for _, entry := range entries {
+		src := entry.source
+		info, oserr := os.Stat(src)
+		if oserr != nil {
+			emitter.Die()
+			return step.fail(dest, oserr)
+		}
+		emitter.EmitFile(
+			entry.name,
+			info,
+			entry.piece.CompressionMethod,
+			entry.piece.CompressionLevel,
+			func(into io.Writer) herr.BuildError {
+				inf, oserr := os.Open(src)
+				if oserr != nil {
+					return step.fail(dest, oserr)
 				}
-			}
-			emitter.EmitFile(
-				destTail,
-				func(into io.Writer) herr.BuildError {
-					inf, oserr := os.Open(src)
-					if oserr != nil {
-						return step.fail(dest, oserr)
-					}
-					_, oserr = io.Copy(into, inf)
-					if oserr != nil {
-						return step.fail(dest, oserr)
-					}
-					return nil
-				},
-				func(oserr error) herr.BuildError {
+				defer inf.Close()
+				_, oserr = io.Copy(into, inf)
+				if oserr != nil {
 					return step.fail(dest, oserr)
-				},
-			)
-		}
+				}
+				return nil
+			},
+			func(oserr error) herr.BuildError {
+				return step.fail(dest, oserr)
+			},
+		)
 	}
 	return emitter.Finish(func(oserr error) herr.BuildError {
 		return step.fail(dest, oserr)
@@ -233,15 +409,71 @@ type ZipPiece struct {
 	BasenameRegex *regexp.Regexp
 	BasenameRegexText string
 	BasenameReplacement string
+	CompressionMethod uint16
+	CompressionLevel int
 }
 
 func (piece *ZipPiece) AddSource(source abs.Artifact) {
 	piece.Sources = append(piece.Sources, source)
 }
 
+func (piece *ZipPiece) EntryName(src string) string {
+	srcTail := filepath.ToSlash(con.ForceToRelativeAndRebase(src, piece.RebaseFrom))
+	destTail := filepath.ToSlash(piece.RebaseTo) + path.Clean("/" + srcTail)
+	if piece.BasenameRegex != nil {
+		destDirname, destBasename := path.Split(path.Clean(destTail))
+		if len(destBasename) > 0 {
+			newTail := piece.BasenameRegex.ReplaceAllString(destBasename, piece.BasenameReplacement)
+			destTail = destDirname + newTail
+		}
+	}
+	return destTail
+}
+
+func (piece *ZipPiece) DumpPiece(prn *herr.ErrorPrinter, level uint) {
+	prn.Indent(level)
+	prn.Println("piece {")
+	prn.Indent(level + 1)
+	prn.Print("from ")
+	con.PrintErrorString(prn, piece.RebaseFrom)
+	if len(piece.RebaseTo) > 0 {
+		prn.Println()
+		prn.Indent(level + 1)
+		prn.Print("to ")
+		con.PrintErrorString(prn, piece.RebaseTo)
+	}
+	if piece.BasenameRegex != nil {
+		prn.Println()
+		prn.Indent(level + 1)
+		prn.Print("rename ")
+		con.PrintErrorString(prn, piece.BasenameRegexText)
+		prn.Print(" ")
+		con.PrintErrorString(prn, piece.BasenameReplacement)
+	}
+	if piece.CompressionMethod == zip.Store {
+		prn.Println()
+		prn.Indent(level + 1)
+		prn.Print("method \"store\"")
+	}
+	if piece.CompressionLevel != ZIP_DEFAULT_LEVEL {
+		prn.Println()
+		prn.Indent(level + 1)
+		prn.Printf("level %d", piece.CompressionLevel)
+	}
+	for _, source := range piece.Sources {
+		prn.Println()
+		prn.Indent(level + 1)
+		con.PrintErrorString(prn, source.ArtifactKey().Unified())
+	}
+	prn.Println()
+	prn.Indent(level)
+	prn.Print("}")
+}
+
 type ZipTransform struct {
 	con.TransformBase
 	Pieces []*ZipPiece
+	Options ZipOptions
 }
 
 func NewZipTransform(description string, arise *herr.AriseRef, pieces []*ZipPiece) *ZipTransform {
@@ -273,6 +505,7 @@ func (xform *ZipTransform) Plan(destination abs.Artifact, plan *abs.Plan) herr.B
 		func() herr.BuildError {
 			step := &ZipStep {
 				Pieces: xform.Pieces,
+				Options: xform.Options,
 				Destination: destination,
 				Arise: xform.Arise,
 			}
@@ -295,37 +528,40 @@ func (xform *ZipTransform) DumpTransform(level uint) error {
 	prn.Print("zip ")
 	con.PrintErrorString(prn, xform.Description)
 	prn.Print(" {")
-	for _, piece := range xform.Pieces {
+	haveOpts := false
+	if xform.Options.FixedTime {
 		prn.Println()
 		prn.Indent(1)
-		prn.Println("piece {")
-		prn.Indent(2)
-		prn.Print("from ")
-		con.PrintErrorString(prn, piece.RebaseFrom)
-		if len(piece.RebaseTo) > 0 {
-			prn.Println()
-			prn.Indent(2)
-			prn.Print("to ")
-			con.PrintErrorString(prn, piece.RebaseTo)
-		}
-		if piece.BasenameRegex != nil {
-			prn.Println()
-			prn.Indent(2)
-			prn.Print("rename ")
-			con.PrintErrorString(prn, piece.BasenameRegexText)
+		prn.Print("fixedTime")
+		if len(xform.Options.TimestampText) > 0 {
 			prn.Print(" ")
-			con.PrintErrorString(prn, piece.BasenameReplacement)
-		}
-		for _, source := range piece.Sources {
-			prn.Println()
-			prn.Indent(2)
-			con.PrintErrorString(prn, source.ArtifactKey().Unified())
+			con.PrintErrorString(prn, xform.Options.TimestampText)
 		}
+		haveOpts = true
+	}
+	if xform.Options.Sorted {
+		prn.Println()
+		prn.Indent(1)
+		prn.Print("sorted")
+		haveOpts = true
+	}
+	if xform.Options.PreserveModes {
 		prn.Println()
 		prn.Indent(1)
-		prn.Print("}")
+		prn.Print("preserveModes")
+		haveOpts = true
+	}
+	if xform.Options.SkipDirectories {
+		prn.Println()
+		prn.Indent(1)
+		prn.Print("skipDirectories")
+		haveOpts = true
+	}
+	for _, piece := range xform.Pieces {
+		prn.Println()
+		piece.DumpPiece(prn, 1)
 	}
-	if len(xform.Pieces) > 0 {
+	if haveOpts || len(xform.Pieces) > 0 {
 		prn.Println()
 		prn.Indent(0)
 	}
@@ -18,5 +18,7 @@ type ArtifactFactory interface {
 
 type FileFilter interface {
 	AcceptFile(fullPath string, baseDir string, info os.FileInfo) bool
+	PruneDirectory(fullPath string, baseDir string, info os.FileInfo) bool
+	PrepareFilter() herr.BuildError
 	DumpFilter(level uint) error
 }
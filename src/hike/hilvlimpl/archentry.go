@@ -0,0 +1,486 @@
+package hilvlimpl
+
+import (
+	"os"
+	"io"
+	"fmt"
+	"time"
+	"bufio"
+	"bytes"
+	"archive/zip"
+	"path/filepath"
+	herr "hike/error"
+	loc "hike/location"
+	abs "hike/abstract"
+	con "hike/concrete"
+)
+
+// ---------------------------------------- BuildError ----------------------------------------
+
+type ReadArchiveError struct {
+	herr.BuildErrorBase
+	Archive string
+	LibError error
+	OperationArise *herr.AriseRef
+}
+
+func (read *ReadArchiveError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Println("Failed to read archive")
+	prn.Indent(1)
+	prn.Println(read.Archive)
+	prn.Indent(0)
+	prn.Print("in operation ")
+	prn.Arise(read.OperationArise, 0)
+	prn.Println()
+	prn.Indent(0)
+	prn.Printf("because: %s", read.LibError.Error())
+	read.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (read *ReadArchiveError) BuildErrorLocation() *loc.Location {
+	return read.OperationArise.Location
+}
+
+var _ herr.BuildError = &ReadArchiveError{}
+
+type NoSuchArchiveEntryError struct {
+	herr.BuildErrorBase
+	Archive string
+	Entry string
+	EntryArise *herr.AriseRef
+}
+
+func (nosuch *NoSuchArchiveEntryError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Printf("No regular file entry '%s' in archive", nosuch.Entry)
+	prn.Println()
+	prn.Indent(1)
+	prn.Println(nosuch.Archive)
+	prn.Indent(0)
+	prn.Print("for artifact ")
+	prn.Arise(nosuch.EntryArise, 0)
+	nosuch.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (nosuch *NoSuchArchiveEntryError) BuildErrorLocation() *loc.Location {
+	return nosuch.EntryArise.Location
+}
+
+var _ herr.BuildError = &NoSuchArchiveEntryError{}
+
+type ArchiveNotAvailableError struct {
+	herr.BuildErrorBase
+	Archive string
+	ScanArise *herr.AriseRef
+}
+
+func (unavailable *ArchiveNotAvailableError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Printf("Archive '%s' is not available for scanning", unavailable.Archive)
+	prn.Println()
+	prn.Indent(0)
+	prn.Print("for scan ")
+	prn.Arise(unavailable.ScanArise, 0)
+	prn.Println()
+	prn.Indent(0)
+	prn.Print("as it must be defined before the scan and be a single file that exists at parse time (archives produced by the build cannot be scanned)")
+	unavailable.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (unavailable *ArchiveNotAvailableError) BuildErrorLocation() *loc.Location {
+	return unavailable.ScanArise.Location
+}
+
+var _ herr.BuildError = &ArchiveNotAvailableError{}
+
+// ---------------------------------------- Archive ----------------------------------------
+
+type ArchiveMember struct {
+	Path string
+	Info os.FileInfo
+	Mode os.FileMode
+	Open func() (io.ReadCloser, error)
+}
+
+func IsZipArchive(archive string) (bool, error) {
+	inf, err := os.Open(archive)
+	if err != nil {
+		return false, err
+	}
+	defer inf.Close()
+	magic, err := bufio.NewReader(inf).Peek(4)
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	return bytes.Equal(magic, []byte("PK\x03\x04")) || bytes.Equal(magic, []byte("PK\x05\x06")), nil
+}
+
+func walkZipArchive(archive string, visit func(*ArchiveMember) (bool, error)) error {
+	zrd, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer zrd.Close()
+	for _, zfile := range zrd.File {
+		fwrap := newUnzippableFile(zfile)
+		if len(fwrap.Path) == 0 {
+			continue
+		}
+		more, err := visit(&ArchiveMember {
+			Path: fwrap.Path,
+			Info: fwrap,
+			Mode: zfile.Mode(),
+			Open: zfile.Open,
+		})
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkTarArchive(archive string, visit func(*ArchiveMember) (bool, error)) error {
+	inf, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer inf.Close()
+	trd, err := OpenTarReader(inf)
+	if err != nil {
+		return err
+	}
+	for {
+		header, err := trd.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		entryPath, _, _ := SplitArchiveEntryName(header.Name)
+		if len(entryPath) == 0 {
+			continue
+		}
+		info := header.FileInfo()
+		more, err := visit(&ArchiveMember {
+			Path: entryPath,
+			Info: info,
+			Mode: info.Mode(),
+			Open: func() (io.ReadCloser, error) {
+				return io.NopCloser(trd), nil
+			},
+		})
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+func WalkArchive(archive string, visit func(*ArchiveMember) (bool, error)) error {
+	isZip, err := IsZipArchive(archive)
+	switch {
+		case err != nil:
+			return err
+		case isZip:
+			return walkZipArchive(archive, visit)
+		default:
+			return walkTarArchive(archive, visit)
+	}
+}
+
+func FindArchiveMember(archive string, entry string, withMember func(*ArchiveMember) error) (bool, error) {
+	found := false
+	err := WalkArchive(archive, func(member *ArchiveMember) (bool, error) {
+		if member.Path != entry || !member.Mode.IsRegular() {
+			return true, nil
+		}
+		found = true
+		return false, withMember(member)
+	})
+	return found, err
+}
+
+type archiveIndex struct {
+	modTime time.Time
+	size int64
+	entries map[string]time.Time
+}
+
+var archiveIndices = make(map[string]*archiveIndex)
+
+func ArchiveEntryModTime(archive string, info os.FileInfo, entry string) (time.Time, bool, error) {
+	index := archiveIndices[archive]
+	if index == nil || !index.modTime.Equal(info.ModTime()) || index.size != info.Size() {
+		index = &archiveIndex {
+			modTime: info.ModTime(),
+			size: info.Size(),
+			entries: make(map[string]time.Time),
+		}
+		err := WalkArchive(archive, func(member *ArchiveMember) (bool, error) {
+			if member.Mode.IsRegular() {
+				if _, seen := index.entries[member.Path]; !seen {
+					index.entries[member.Path] = member.Info.ModTime()
+				}
+			}
+			return true, nil
+		})
+		if err != nil {
+			delete(archiveIndices, archive)
+			return time.Time{}, false, err
+		}
+		archiveIndices[archive] = index
+	}
+	stamp, found := index.entries[entry]
+	return stamp, found, nil
+}
+
+// ---------------------------------------- Step ----------------------------------------
+
+type ExtractArchiveEntryStep struct {
+	con.StepBase
+	Archive string
+	Entry string
+	CachePath string
+	Arise *herr.AriseRef
+}
+
+func (step *ExtractArchiveEntryStep) fail(err error) herr.BuildError {
+	return &ReadArchiveError {
+		Archive: step.Archive,
+		LibError: err,
+		OperationArise: step.Arise,
+	}
+}
+
+func (step *ExtractArchiveEntryStep) Perform() herr.BuildError {
+	err := con.MakeEnclosingDirectories(step.CachePath, step.Arise)
+	if err != nil {
+		return err
+	}
+	tmpPath := step.CachePath + ".tmp"
+	found, nerr := FindArchiveMember(step.Archive, step.Entry, func(member *ArchiveMember) error {
+		content, err := member.Open()
+		if err != nil {
+			return err
+		}
+		defer content.Close()
+		outf, err := os.OpenFile(tmpPath, os.O_WRONLY | os.O_CREATE | os.O_TRUNC, member.Mode.Perm() | 0200)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(outf, content)
+		if err != nil {
+			outf.Close()
+			return err
+		}
+		return outf.Close()
+	})
+	if nerr != nil {
+		os.Remove(tmpPath)
+		return step.fail(nerr)
+	}
+	if !found {
+		return &NoSuchArchiveEntryError {
+			Archive: step.Archive,
+			Entry: step.Entry,
+			EntryArise: step.Arise,
+		}
+	}
+	nerr = os.Rename(tmpPath, step.CachePath)
+	if nerr != nil {
+		os.Remove(tmpPath)
+		return step.fail(nerr)
+	}
+	return nil
+}
+
+var _ abs.Step = &ExtractArchiveEntryStep{}
+
+// ---------------------------------------- Artifact ----------------------------------------
+
+type ArchiveEntryArtifact struct {
+	con.ArtifactBase
+	Archive abs.Artifact
+	Entry string
+	CachePath string
+}
+
+func NewArchiveEntryArtifact(
+	key abs.ArtifactKey,
+	name string,
+	arise *herr.AriseRef,
+	archive abs.Artifact,
+	entry string,
+	cachePath string,
+) *ArchiveEntryArtifact {
+	artifact := &ArchiveEntryArtifact {
+		Archive: archive,
+		Entry: entry,
+		CachePath: cachePath,
+	}
+	artifact.Key = key
+	artifact.ID = abs.NextArtifactID()
+	artifact.Name = name
+	artifact.Arise = arise
+	return artifact
+}
+
+func (artifact *ArchiveEntryArtifact) DisplayName() string {
+	if len(artifact.Name) > 0 {
+		return artifact.Name
+	} else {
+		return artifact.Archive.DisplayName() + "!" + artifact.Entry
+	}
+}
+
+func (artifact *ArchiveEntryArtifact) PathNames(sink []string) ([]string, herr.BuildError) {
+	return append(sink, artifact.CachePath), nil
+}
+
+func (artifact *ArchiveEntryArtifact) archivePath(arise *herr.AriseRef) (string, herr.BuildError) {
+	archPaths, err := artifact.Archive.PathNames(nil)
+	if err != nil {
+		return "", err
+	}
+	if len(archPaths) != 1 {
+		return "", &con.ConflictingDestinationsError {
+			Operation: "read archive entry " + artifact.Entry,
+			OperationArise: arise,
+			PathCount: uint(len(archPaths)),
+			PathsAreDestinations: false,
+		}
+	}
+	return archPaths[0], nil
+}
+
+func (artifact *ArchiveEntryArtifact) ModTime(arise *herr.AriseRef) (stamp time.Time, err herr.BuildError, missing bool) {
+	archive, err := artifact.archivePath(arise)
+	if err != nil {
+		return
+	}
+	info, oserr := os.Stat(archive)
+	if os.IsNotExist(oserr) {
+		missing = true
+		return
+	}
+	var found bool
+	if oserr == nil {
+		stamp, found, oserr = ArchiveEntryModTime(archive, info, artifact.Entry)
+	}
+	switch {
+		case oserr != nil:
+			err = &ReadArchiveError {
+				Archive: archive,
+				LibError: oserr,
+				OperationArise: arise,
+			}
+		case !found:
+			err = &NoSuchArchiveEntryError {
+				Archive: archive,
+				Entry: artifact.Entry,
+				EntryArise: artifact.Arise,
+			}
+	}
+	return
+}
+
+func (artifact *ArchiveEntryArtifact) EarliestModTime(arise *herr.AriseRef) (time.Time, herr.BuildError, bool) {
+	return artifact.ModTime(arise)
+}
+
+func (artifact *ArchiveEntryArtifact) LatestModTime(arise *herr.AriseRef) (time.Time, herr.BuildError, bool) {
+	return artifact.ModTime(arise)
+}
+
+func (artifact *ArchiveEntryArtifact) Flatten() herr.BuildError {
+	return nil
+}
+
+func (artifact *ArchiveEntryArtifact) cacheIsCurrent(arise *herr.AriseRef) (bool, herr.BuildError) {
+	cinfo, oserr := os.Stat(artifact.CachePath)
+	switch {
+		case os.IsNotExist(oserr):
+			return false, nil
+		case oserr != nil:
+			return false, &con.CannotStatError {
+				Path: artifact.CachePath,
+				OSError: oserr,
+				OperationArise: arise,
+			}
+	}
+	amod, err, amiss := artifact.Archive.LatestModTime(arise)
+	if err != nil || amiss {
+		return false, err
+	}
+	return !amod.After(cinfo.ModTime()), nil
+}
+
+func (artifact *ArchiveEntryArtifact) Require(plan *abs.Plan, requireArise *herr.AriseRef) (err herr.BuildError) {
+	if plan.AlreadyUpToDate(artifact) {
+		return
+	}
+	stepCount := plan.StepCount()
+	err = artifact.Archive.Require(plan, requireArise)
+	current := false
+	if err == nil && plan.StepCount() == stepCount {
+		current, err = artifact.cacheIsCurrent(requireArise)
+	}
+	if err != nil {
+		err.AddErrorFrame(&con.RequireArtifactFrame {
+			Artifact: artifact,
+		})
+		return
+	}
+	if !current {
+		archive, err := artifact.archivePath(artifact.Arise)
+		if err != nil {
+			return err
+		}
+		step := &ExtractArchiveEntryStep {
+			Archive: archive,
+			Entry: artifact.Entry,
+			CachePath: artifact.CachePath,
+			Arise: artifact.Arise,
+		}
+		step.Description = fmt.Sprintf(
+			"[%s] extract %s from %s",
+			artifact.Key.Project,
+			artifact.Entry,
+			filepath.Base(archive),
+		)
+		plan.AddStep(step)
+	}
+	plan.BroughtUpToDate(artifact)
+	return
+}
+
+func (artifact *ArchiveEntryArtifact) DumpArtifact(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Out = os.Stdout
+	prn.Level(level)
+	prn.Print("archiveEntry ")
+	con.PrintErrorString(prn, artifact.Key.Unified())
+	prn.Println(" {")
+	prn.Indent(1)
+	con.PrintErrorString(prn, artifact.Archive.ArtifactKey().Unified())
+	prn.Println()
+	prn.Indent(1)
+	con.PrintErrorString(prn, artifact.Entry)
+	prn.Println()
+	prn.Indent(1)
+	prn.Print("name ")
+	con.PrintErrorString(prn, artifact.Name)
+	prn.Println()
+	prn.Indent(0)
+	prn.Print("}")
+	return prn.Done()
+}
+
+var _ abs.Artifact = &ArchiveEntryArtifact{}
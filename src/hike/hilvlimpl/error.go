@@ -36,6 +36,37 @@ func (illegal *IllegalRegexError) BuildErrorLocation() *loc.Location {
 
 var _ herr.BuildError = &IllegalRegexError{}
 
+// ---------------------------------------- IllegalGlobError ----------------------------------------
+
+type IllegalGlobError struct {
+	herr.BuildErrorBase
+	Glob string
+	LibError error
+	PatternArise *herr.AriseRef
+}
+
+func (illegal *IllegalGlobError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Println("Illegal glob pattern")
+	prn.Indent(1)
+	prn.Println(illegal.Glob)
+	prn.Indent(0)
+	prn.Print("in pattern ")
+	prn.Arise(illegal.PatternArise, 0)
+	prn.Println()
+	prn.Indent(0)
+	prn.Printf("with runtime saying: %s", illegal.LibError.Error())
+	illegal.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (illegal *IllegalGlobError) BuildErrorLocation() *loc.Location {
+	return illegal.PatternArise.Location
+}
+
+var _ herr.BuildError = &IllegalGlobError{}
+
 // ---------------------------------------- FSWalkError ----------------------------------------
 
 type FSWalkError struct {
@@ -66,3 +97,83 @@ func (walk *FSWalkError) BuildErrorLocation() *loc.Location {
 }
 
 var _ herr.BuildError = &FSWalkError{}
+
+// ---------------------------------------- IllegalSizeSpecError ----------------------------------------
+
+type IllegalSizeSpecError struct {
+	herr.BuildErrorBase
+	Specifier string
+	LibError error
+	Location *loc.Location
+}
+
+func (illegal *IllegalSizeSpecError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Printf("Illegal file size specifier '%s':\n", illegal.Specifier)
+	prn.Indent(1)
+	prn.Println(illegal.LibError.Error())
+	prn.Indent(0)
+	prn.Print("at ")
+	prn.Location(illegal.Location)
+	illegal.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (illegal *IllegalSizeSpecError) BuildErrorLocation() *loc.Location {
+	return illegal.Location
+}
+
+var _ herr.BuildError = &IllegalSizeSpecError{}
+
+// ---------------------------------------- UnresolvedFilterReferenceError ----------------------------------------
+
+type UnresolvedFilterReferenceError struct {
+	herr.BuildErrorBase
+	FilterArise *herr.AriseRef
+}
+
+func (unresolved *UnresolvedFilterReferenceError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Println("Reference artifact of file filter is not defined yet")
+	prn.Indent(0)
+	prn.Print("for filter ")
+	prn.Arise(unresolved.FilterArise, 0)
+	unresolved.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (unresolved *UnresolvedFilterReferenceError) BuildErrorLocation() *loc.Location {
+	return unresolved.FilterArise.Location
+}
+
+var _ herr.BuildError = &UnresolvedFilterReferenceError{}
+
+// ---------------------------------------- IllegalDurationError ----------------------------------------
+
+type IllegalDurationError struct {
+	herr.BuildErrorBase
+	Specifier string
+	LibError error
+	Location *loc.Location
+}
+
+func (illegal *IllegalDurationError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Printf("Illegal duration '%s':\n", illegal.Specifier)
+	prn.Indent(1)
+	prn.Println(illegal.LibError.Error())
+	prn.Indent(0)
+	prn.Print("at ")
+	prn.Location(illegal.Location)
+	illegal.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (illegal *IllegalDurationError) BuildErrorLocation() *loc.Location {
+	return illegal.Location
+}
+
+var _ herr.BuildError = &IllegalDurationError{}
@@ -2,9 +2,13 @@ package hilvlimpl
 
 import (
 	"os"
+	"fmt"
+	"time"
+	"regexp"
 	"path/filepath"
 	herr "hike/error"
 	hlv "hike/hilevel"
+	abs "hike/abstract"
 	con "hike/concrete"
 )
 
@@ -24,6 +28,14 @@ func (filter *FileTypeFilter) AcceptFile(fullPath string, baseDir string, info o
 	return info.IsDir() == filter.IsDir
 }
 
+func (filter *FileTypeFilter) PruneDirectory(fullPath string, baseDir string, info os.FileInfo) bool {
+	return false
+}
+
+func (filter *FileTypeFilter) PrepareFilter() herr.BuildError {
+	return nil
+}
+
 func (filter *FileTypeFilter) DumpFilter(level uint) error {
 	prn := herr.NewErrorPrinter()
 	prn.Out = os.Stdout
@@ -54,6 +66,14 @@ func (filter *WildcardFileFilter) AcceptFile(fullPath string, baseDir string, in
 	return err == nil && matched
 }
 
+func (filter *WildcardFileFilter) PruneDirectory(fullPath string, baseDir string, info os.FileInfo) bool {
+	return false
+}
+
+func (filter *WildcardFileFilter) PrepareFilter() herr.BuildError {
+	return nil
+}
+
 func (filter *WildcardFileFilter) DumpFilter(level uint) error {
 	prn := herr.NewErrorPrinter()
 	prn.Out = os.Stdout
@@ -64,6 +84,304 @@ func (filter *WildcardFileFilter) DumpFilter(level uint) error {
 
 var _ hlv.FileFilter = &WildcardFileFilter{}
 
+// ---------------------------------------- RegexFileFilter ----------------------------------------
+
+type RegexFileFilter struct {
+	Regex *regexp.Regexp
+	RegexText string
+}
+
+func NewRegexFileFilter(regex *regexp.Regexp, regexText string) *RegexFileFilter {
+	return &RegexFileFilter {
+		Regex: regex,
+		RegexText: regexText,
+	}
+}
+
+func (filter *RegexFileFilter) AcceptFile(fullPath string, baseDir string, info os.FileInfo) bool {
+	return filter.Regex.MatchString(filepath.ToSlash(FilterRelativePath(fullPath, baseDir)))
+}
+
+func (filter *RegexFileFilter) PruneDirectory(fullPath string, baseDir string, info os.FileInfo) bool {
+	return false
+}
+
+func (filter *RegexFileFilter) PrepareFilter() herr.BuildError {
+	return nil
+}
+
+func (filter *RegexFileFilter) DumpFilter(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Out = os.Stdout
+	prn.Print("regex ")
+	con.PrintErrorString(prn, filter.RegexText)
+	return prn.Done()
+}
+
+var _ hlv.FileFilter = &RegexFileFilter{}
+
+// ---------------------------------------- SizeFileFilter ----------------------------------------
+
+const (
+	SIZECMP_LESS = iota
+	SIZECMP_LESS_EQUAL
+	SIZECMP_EQUAL
+	SIZECMP_NOT_EQUAL
+	SIZECMP_GREATER_EQUAL
+	SIZECMP_GREATER
+)
+
+var sizeComparisonOperators = []string {
+	SIZECMP_LESS: "<",
+	SIZECMP_LESS_EQUAL: "<=",
+	SIZECMP_EQUAL: "==",
+	SIZECMP_NOT_EQUAL: "!=",
+	SIZECMP_GREATER_EQUAL: ">=",
+	SIZECMP_GREATER: ">",
+}
+
+var sizeSpecRegex = regexp.MustCompile(`^\s*(<=|>=|==|!=|<|>|=)?\s*([0-9]+)\s*([kKmMgGtT]?)[bB]?\s*$`)
+
+func ParseSizeSpec(spec string) (comparison int, size int64, err error) {
+	groups := sizeSpecRegex.FindStringSubmatch(spec)
+	if groups == nil {
+		err = fmt.Errorf("expected optional comparison operator, size and optional unit (k, M, G or T)")
+		return
+	}
+	switch groups[1] {
+		case "<":
+			comparison = SIZECMP_LESS
+		case "<=":
+			comparison = SIZECMP_LESS_EQUAL
+		case "", "=", "==":
+			comparison = SIZECMP_EQUAL
+		case "!=":
+			comparison = SIZECMP_NOT_EQUAL
+		case ">=":
+			comparison = SIZECMP_GREATER_EQUAL
+		case ">":
+			comparison = SIZECMP_GREATER
+	}
+	_, err = fmt.Sscan(groups[2], &size)
+	if err != nil {
+		return
+	}
+	switch groups[3] {
+		case "t", "T":
+			size *= 1024
+			fallthrough
+		case "g", "G":
+			size *= 1024
+			fallthrough
+		case "m", "M":
+			size *= 1024
+			fallthrough
+		case "k", "K":
+			size *= 1024
+	}
+	return
+}
+
+type SizeFileFilter struct {
+	Comparison int
+	Size int64
+}
+
+func NewSizeFileFilter(comparison int, size int64) *SizeFileFilter {
+	return &SizeFileFilter {
+		Comparison: comparison,
+		Size: size,
+	}
+}
+
+func (filter *SizeFileFilter) AcceptFile(fullPath string, baseDir string, info os.FileInfo) bool {
+	size := info.Size()
+	switch filter.Comparison {
+		case SIZECMP_LESS:
+			return size < filter.Size
+		case SIZECMP_LESS_EQUAL:
+			return size <= filter.Size
+		case SIZECMP_EQUAL:
+			return size == filter.Size
+		case SIZECMP_NOT_EQUAL:
+			return size != filter.Size
+		case SIZECMP_GREATER_EQUAL:
+			return size >= filter.Size
+		case SIZECMP_GREATER:
+			return size > filter.Size
+		default:
+			panic(fmt.Sprintf("Unrecognized size comparison: %d", filter.Comparison))
+	}
+}
+
+func (filter *SizeFileFilter) PruneDirectory(fullPath string, baseDir string, info os.FileInfo) bool {
+	return false
+}
+
+func (filter *SizeFileFilter) PrepareFilter() herr.BuildError {
+	return nil
+}
+
+func (filter *SizeFileFilter) DumpFilter(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Out = os.Stdout
+	prn.Print("size ")
+	con.PrintErrorString(prn, fmt.Sprintf("%s%d", sizeComparisonOperators[filter.Comparison], filter.Size))
+	return prn.Done()
+}
+
+var _ hlv.FileFilter = &SizeFileFilter{}
+
+// ---------------------------------------- AgeFileFilter ----------------------------------------
+
+type AgeFileFilter struct {
+	Newer bool
+	MaxAge time.Duration
+	ByReference bool
+	Reference abs.Artifact
+	Arise *herr.AriseRef
+	threshold time.Time
+	haveThreshold bool
+}
+
+func NewAgeFileFilter(newer bool, maxAge time.Duration, arise *herr.AriseRef) *AgeFileFilter {
+	return &AgeFileFilter {
+		Newer: newer,
+		MaxAge: maxAge,
+		Arise: arise,
+	}
+}
+
+func NewAgeReferenceFileFilter(newer bool, arise *herr.AriseRef) *AgeFileFilter {
+	return &AgeFileFilter {
+		Newer: newer,
+		ByReference: true,
+		Arise: arise,
+	}
+}
+
+func (filter *AgeFileFilter) PrepareFilter() herr.BuildError {
+	if !filter.ByReference {
+		filter.threshold = time.Now().Add(-filter.MaxAge)
+		filter.haveThreshold = true
+		return nil
+	}
+	if filter.Reference == nil {
+		return &UnresolvedFilterReferenceError {
+			FilterArise: filter.Arise,
+		}
+	}
+	var err herr.BuildError
+	var missing bool
+	if filter.Newer {
+		filter.threshold, err, missing = filter.Reference.LatestModTime(filter.Arise)
+	} else {
+		filter.threshold, err, missing = filter.Reference.EarliestModTime(filter.Arise)
+	}
+	filter.haveThreshold = err == nil && !missing
+	return err
+}
+
+func (filter *AgeFileFilter) AcceptFile(fullPath string, baseDir string, info os.FileInfo) bool {
+	switch {
+		case !filter.haveThreshold:
+			return filter.Newer
+		case filter.Newer:
+			return info.ModTime().After(filter.threshold)
+		default:
+			return info.ModTime().Before(filter.threshold)
+	}
+}
+
+func (filter *AgeFileFilter) PruneDirectory(fullPath string, baseDir string, info os.FileInfo) bool {
+	return false
+}
+
+func (filter *AgeFileFilter) DumpFilter(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Out = os.Stdout
+	if filter.Newer {
+		prn.Print("newerThan ")
+	} else {
+		prn.Print("olderThan ")
+	}
+	if filter.ByReference {
+		prn.Print("artifact ")
+		if filter.Reference != nil {
+			con.PrintErrorString(prn, filter.Reference.ArtifactKey().Unified())
+		}
+	} else {
+		con.PrintErrorString(prn, filter.MaxAge.String())
+	}
+	return prn.Done()
+}
+
+var _ hlv.FileFilter = &AgeFileFilter{}
+
+// ---------------------------------------- ModeFileFilter ----------------------------------------
+
+const (
+	MODEFILTER_EXECUTABLE = iota
+	MODEFILTER_SYMLINK
+	MODEFILTER_EMPTY
+)
+
+type ModeFileFilter struct {
+	Kind int
+}
+
+func NewModeFileFilter(kind int) *ModeFileFilter {
+	return &ModeFileFilter {
+		Kind: kind,
+	}
+}
+
+func (filter *ModeFileFilter) AcceptFile(fullPath string, baseDir string, info os.FileInfo) bool {
+	switch filter.Kind {
+		case MODEFILTER_EXECUTABLE:
+			return info.Mode().IsRegular() && info.Mode() & 0111 != 0
+		case MODEFILTER_SYMLINK:
+			return info.Mode() & os.ModeSymlink != 0
+		case MODEFILTER_EMPTY:
+			if !info.IsDir() {
+				return info.Mode().IsRegular() && info.Size() == 0
+			}
+			dir, err := os.Open(fullPath)
+			if err != nil {
+				return false
+			}
+			defer dir.Close()
+			names, err := dir.Readdirnames(1)
+			return len(names) == 0 && err != nil
+		default:
+			panic(fmt.Sprintf("Unrecognized file mode filter: %d", filter.Kind))
+	}
+}
+
+func (filter *ModeFileFilter) PruneDirectory(fullPath string, baseDir string, info os.FileInfo) bool {
+	return false
+}
+
+func (filter *ModeFileFilter) PrepareFilter() herr.BuildError {
+	return nil
+}
+
+func (filter *ModeFileFilter) DumpFilter(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Out = os.Stdout
+	switch filter.Kind {
+		case MODEFILTER_EXECUTABLE:
+			prn.Print("executable")
+		case MODEFILTER_SYMLINK:
+			prn.Print("symlink")
+		case MODEFILTER_EMPTY:
+			prn.Print("empty")
+	}
+	return prn.Done()
+}
+
+var _ hlv.FileFilter = &ModeFileFilter{}
+
 // ---------------------------------------- AnyFileFilter ----------------------------------------
 
 type AnyFileFilter struct {
@@ -85,6 +403,22 @@ func (filter *AnyFileFilter) AcceptFile(fullPath string, baseDir string, info os
 	return false
 }
 
+func (filter *AnyFileFilter) PruneDirectory(fullPath string, baseDir string, info os.FileInfo) bool {
+	if len(filter.Children) == 0 {
+		return false
+	}
+	for _, child := range filter.Children {
+		if !child.PruneDirectory(fullPath, baseDir, info) {
+			return false
+		}
+	}
+	return true
+}
+
+func (filter *AnyFileFilter) PrepareFilter() herr.BuildError {
+	return PrepareFileFilters(filter.Children)
+}
+
 func (filter *AnyFileFilter) DumpFilter(level uint) error {
 	prn := herr.NewErrorPrinter()
 	prn.Out = os.Stdout
@@ -126,6 +460,14 @@ func (filter *AllFileFilter) AcceptFile(fullPath string, baseDir string, info os
 	return true
 }
 
+func (filter *AllFileFilter) PruneDirectory(fullPath string, baseDir string, info os.FileInfo) bool {
+	return AnyFileFilterPrunes(fullPath, baseDir, info, filter.Children)
+}
+
+func (filter *AllFileFilter) PrepareFilter() herr.BuildError {
+	return PrepareFileFilters(filter.Children)
+}
+
 func (filter *AllFileFilter) DumpFilter(level uint) error {
 	prn := herr.NewErrorPrinter()
 	prn.Out = os.Stdout
@@ -146,7 +488,7 @@ func (filter *AllFileFilter) DumpFilter(level uint) error {
 
 var _ hlv.FileFilter = &AllFileFilter{}
 
-// ---------------------------------------- AllFileFilter ----------------------------------------
+// ---------------------------------------- NotFileFilter ----------------------------------------
 
 type NotFileFilter struct {
 	Child hlv.FileFilter
@@ -162,6 +504,14 @@ func (filter *NotFileFilter) AcceptFile(fullPath string, baseDir string, info os
 	return !filter.Child.AcceptFile(fullPath, baseDir, info)
 }
 
+func (filter *NotFileFilter) PruneDirectory(fullPath string, baseDir string, info os.FileInfo) bool {
+	return false
+}
+
+func (filter *NotFileFilter) PrepareFilter() herr.BuildError {
+	return filter.Child.PrepareFilter()
+}
+
 func (filter *NotFileFilter) DumpFilter(level uint) error {
 	prn := herr.NewErrorPrinter()
 	prn.Out = os.Stdout
@@ -174,6 +524,16 @@ var _ hlv.FileFilter = &NotFileFilter{}
 
 // ---------------------------------------- misc ----------------------------------------
 
+func PrepareFileFilters(filters []hlv.FileFilter) herr.BuildError {
+	for _, filter := range filters {
+		err := filter.PrepareFilter()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func AllFileFilters(fullPath string, baseDir string, info os.FileInfo, filters []hlv.FileFilter) bool {
 	for _, filter := range filters {
 		if !filter.AcceptFile(fullPath, baseDir, info) {
@@ -182,3 +542,12 @@ func AllFileFilters(fullPath string, baseDir string, info os.FileInfo, filters [
 	}
 	return true
 }
+
+func AnyFileFilterPrunes(fullPath string, baseDir string, info os.FileInfo, filters []hlv.FileFilter) bool {
+	for _, filter := range filters {
+		if filter.PruneDirectory(fullPath, baseDir, info) {
+			return true
+		}
+	}
+	return false
+}
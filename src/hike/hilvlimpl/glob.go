@@ -0,0 +1,255 @@
+package hilvlimpl
+
+import (
+	"os"
+	"path"
+	"strings"
+	"path/filepath"
+	herr "hike/error"
+	hlv "hike/hilevel"
+	con "hike/concrete"
+)
+
+// ---------------------------------------- glob matching ----------------------------------------
+
+func skipGlobClass(pattern string, start int) int {
+	index := start + 1
+	if index < len(pattern) && (pattern[index] == '!' || pattern[index] == '^') {
+		index++
+	}
+	if index < len(pattern) && pattern[index] == ']' {
+		index++
+	}
+	for ; index < len(pattern); index++ {
+		switch pattern[index] {
+			case '\\':
+				index++
+			case ']':
+				return index
+		}
+	}
+	return start
+}
+
+func findGlobBrace(pattern string) (open int, close int, commas []int) {
+	open = -1
+	depth := 0
+	for index := 0; index < len(pattern); index++ {
+		switch pattern[index] {
+			case '\\':
+				index++
+			case '[':
+				index = skipGlobClass(pattern, index)
+			case '{':
+				if depth == 0 {
+					open = index
+					commas = nil
+				}
+				depth++
+			case ',':
+				if depth == 1 {
+					commas = append(commas, index)
+				}
+			case '}':
+				if depth > 0 {
+					depth--
+					if depth == 0 {
+						close = index
+						return
+					}
+				}
+		}
+	}
+	open = -1
+	return
+}
+
+func ExpandGlobBraces(pattern string) []string {
+	open, close, commas := findGlobBrace(pattern)
+	if open < 0 {
+		return []string{pattern}
+	}
+	prefix := pattern[:open]
+	suffix := pattern[close + 1:]
+	var expanded []string
+	last := open + 1
+	for _, comma := range append(commas, close) {
+		for _, tail := range ExpandGlobBraces(pattern[last:comma] + suffix) {
+			expanded = append(expanded, prefix + tail)
+		}
+		last = comma + 1
+	}
+	return expanded
+}
+
+func translateGlobSegment(segment string) string {
+	var sink strings.Builder
+	for index := 0; index < len(segment); index++ {
+		switch segment[index] {
+			case '\\':
+				sink.WriteByte('\\')
+				index++
+				if index < len(segment) {
+					sink.WriteByte(segment[index])
+				}
+			case '[':
+				sink.WriteByte('[')
+				if index + 1 < len(segment) && segment[index + 1] == '!' {
+					sink.WriteByte('^')
+					index++
+				}
+			default:
+				sink.WriteByte(segment[index])
+		}
+	}
+	return sink.String()
+}
+
+type GlobPattern struct {
+	alternatives [][]string
+}
+
+func CompileGlob(pattern string) (*GlobPattern, error) {
+	glob := &GlobPattern{}
+	for _, alternative := range ExpandGlobBraces(filepath.ToSlash(pattern)) {
+		var segments []string
+		for _, segment := range strings.Split(strings.Trim(alternative, "/"), "/") {
+			switch {
+				case len(segment) == 0:
+				case segment == "**":
+					if len(segments) == 0 || segments[len(segments) - 1] != "**" {
+						segments = append(segments, segment)
+					}
+				default:
+					segment = translateGlobSegment(segment)
+					_, err := path.Match(segment, "")
+					if err != nil {
+						return nil, err
+					}
+					segments = append(segments, segment)
+			}
+		}
+		glob.alternatives = append(glob.alternatives, segments)
+	}
+	return glob, nil
+}
+
+func matchGlobSegments(pattern []string, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return len(segments) > 0
+			}
+			for skip := 0; skip <= len(segments); skip++ {
+				if matchGlobSegments(pattern, segments[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		matched, err := path.Match(pattern[0], segments[0])
+		if err != nil || !matched {
+			return false
+		}
+		pattern = pattern[1:]
+		segments = segments[1:]
+	}
+	return len(segments) == 0
+}
+
+func matchGlobPrefix(pattern []string, segments []string) bool {
+	for len(segments) > 0 {
+		if len(pattern) == 0 {
+			return false
+		}
+		if pattern[0] == "**" {
+			return true
+		}
+		matched, err := path.Match(pattern[0], segments[0])
+		if err != nil || !matched {
+			return false
+		}
+		pattern = pattern[1:]
+		segments = segments[1:]
+	}
+	return len(pattern) > 0
+}
+
+func splitGlobSubject(subject string) []string {
+	subject = strings.Trim(filepath.ToSlash(subject), "/")
+	if len(subject) == 0 || subject == "." {
+		return nil
+	}
+	return strings.Split(subject, "/")
+}
+
+func (glob *GlobPattern) Match(subject string) bool {
+	segments := splitGlobSubject(subject)
+	for _, alternative := range glob.alternatives {
+		if matchGlobSegments(alternative, segments) {
+			return true
+		}
+	}
+	return false
+}
+
+func (glob *GlobPattern) MatchBelow(directory string) bool {
+	segments := splitGlobSubject(directory)
+	for _, alternative := range glob.alternatives {
+		if matchGlobPrefix(alternative, segments) {
+			return true
+		}
+	}
+	return false
+}
+
+func FilterRelativePath(fullPath string, baseDir string) string {
+	if len(baseDir) == 0 {
+		return fullPath
+	}
+	rel, err := filepath.Rel(baseDir, fullPath)
+	if err != nil {
+		return fullPath
+	}
+	return rel
+}
+
+// ---------------------------------------- GlobFileFilter ----------------------------------------
+
+type GlobFileFilter struct {
+	Pattern string
+	Glob *GlobPattern
+}
+
+func NewGlobFileFilter(pattern string, glob *GlobPattern) *GlobFileFilter {
+	return &GlobFileFilter {
+		Pattern: pattern,
+		Glob: glob,
+	}
+}
+
+func (filter *GlobFileFilter) AcceptFile(fullPath string, baseDir string, info os.FileInfo) bool {
+	return filter.Glob.Match(FilterRelativePath(fullPath, baseDir))
+}
+
+func (filter *GlobFileFilter) PruneDirectory(fullPath string, baseDir string, info os.FileInfo) bool {
+	return !filter.Glob.MatchBelow(FilterRelativePath(fullPath, baseDir))
+}
+
+func (filter *GlobFileFilter) PrepareFilter() herr.BuildError {
+	return nil
+}
+
+func (filter *GlobFileFilter) DumpFilter(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Out = os.Stdout
+	prn.Print("glob ")
+	con.PrintErrorString(prn, filter.Pattern)
+	return prn.Done()
+}
+
+var _ hlv.FileFilter = &GlobFileFilter{}
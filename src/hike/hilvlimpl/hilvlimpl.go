@@ -55,6 +55,7 @@ func (factory *CommandTransformFactory) NewTransform(
 		factory.Loud,
 		factory.SuffixIsDestination,
 	)
+	command.CommandStreams = factory.CommandStreams
 	for _, source := range sources {
 		command.AddSource(source)
 	}
@@ -88,9 +89,7 @@ func (factory *CopyTransformFactory) NewTransform(
 		UIBase: state.Config.TopDir,
 		OwningProject: state.Config.EffectiveProjectName(),
 	}
-	xform.DestinationIsDir = factory.DestinationIsDir
-	xform.RebaseFrom = factory.RebaseFrom
-	xform.Arise = factory.Arise
+	xform.CopyTransformBase = factory.CopyTransformBase
 	return xform, nil
 }
 
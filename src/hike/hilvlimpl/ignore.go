@@ -0,0 +1,169 @@
+package hilvlimpl
+
+import (
+	"os"
+	"bufio"
+	"strings"
+	"path/filepath"
+)
+
+var DefaultIgnoreFiles = []string {
+	".gitignore",
+	".hikeignore",
+}
+
+// ---------------------------------------- IgnoreRule ----------------------------------------
+
+type IgnoreRule struct {
+	BaseDir string
+	Pattern string
+	Glob *GlobPattern
+	Negate bool
+	DirectoryOnly bool
+}
+
+func ParseIgnoreRule(line string, baseDir string) (*IgnoreRule, error) {
+	line = strings.TrimRight(line, "\r")
+	trimmed := strings.TrimRight(line, " \t")
+	if strings.HasSuffix(trimmed, "\\") && len(trimmed) < len(line) {
+		trimmed += " "
+	}
+	if len(trimmed) == 0 || strings.HasPrefix(trimmed, "#") {
+		return nil, nil
+	}
+	rule := &IgnoreRule {
+		BaseDir: baseDir,
+		Pattern: trimmed,
+	}
+	if strings.HasPrefix(trimmed, "!") {
+		rule.Negate = true
+		trimmed = trimmed[1:]
+	}
+	if strings.HasSuffix(trimmed, "/") {
+		rule.DirectoryOnly = true
+		trimmed = strings.TrimRight(trimmed, "/")
+	}
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+	if !strings.Contains(trimmed, "/") {
+		trimmed = "**/" + trimmed
+	}
+	glob, err := CompileGlob(trimmed)
+	if err != nil {
+		return nil, err
+	}
+	rule.Glob = glob
+	return rule, nil
+}
+
+func ParseIgnoreFile(path string, baseDir string) ([]*IgnoreRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	var rules []*IgnoreRule
+	scan := bufio.NewScanner(file)
+	for scan.Scan() {
+		rule, err := ParseIgnoreRule(scan.Text(), baseDir)
+		if err != nil {
+			return nil, err
+		}
+		if rule != nil {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, scan.Err()
+}
+
+func (rule *IgnoreRule) Matches(fullPath string, isDir bool) bool {
+	if rule.DirectoryOnly && !isDir {
+		return false
+	}
+	rel, err := filepath.Rel(rule.BaseDir, fullPath)
+	if err != nil {
+		return false
+	}
+	return rule.Glob.Match(rel)
+}
+
+// ---------------------------------------- IgnoreMatcher ----------------------------------------
+
+type IgnoreMatcher struct {
+	Root string
+	FileNames []string
+	dirRules map[string][]*IgnoreRule
+}
+
+func NewIgnoreMatcher(root string, fileNames []string) *IgnoreMatcher {
+	return &IgnoreMatcher {
+		Root: filepath.Clean(root),
+		FileNames: fileNames,
+		dirRules: make(map[string][]*IgnoreRule),
+	}
+}
+
+func (matcher *IgnoreMatcher) rulesFor(dir string) ([]*IgnoreRule, error) {
+	rules, present := matcher.dirRules[dir]
+	if present {
+		return rules, nil
+	}
+	if dir != matcher.Root {
+		parent := filepath.Dir(dir)
+		if parent != dir {
+			inherited, err := matcher.rulesFor(parent)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, inherited...)
+		}
+	}
+	for _, fileName := range matcher.FileNames {
+		own, err := ParseIgnoreFile(filepath.Join(dir, fileName), dir)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, own...)
+	}
+	matcher.dirRules[dir] = rules
+	return rules, nil
+}
+
+func (matcher *IgnoreMatcher) Ignored(fullPath string, info os.FileInfo) (bool, error) {
+	fullPath = filepath.Clean(fullPath)
+	if fullPath == matcher.Root {
+		return false, nil
+	}
+	if info.IsDir() && info.Name() == ".git" {
+		return true, nil
+	}
+	rules, err := matcher.rulesFor(filepath.Dir(fullPath))
+	if err != nil {
+		return false, err
+	}
+	ignored := false
+	for _, rule := range rules {
+		if rule.Negate == ignored && rule.Matches(fullPath, info.IsDir()) {
+			ignored = !rule.Negate
+		}
+	}
+	return ignored, nil
+}
+
+func (matcher *IgnoreMatcher) SkipWalkEntry(fullPath string, info os.FileInfo) (bool, error) {
+	ignored, err := matcher.Ignored(fullPath, info)
+	switch {
+		case err != nil:
+			return true, err
+		case !ignored:
+			return false, nil
+		case info.IsDir():
+			return true, filepath.SkipDir
+		default:
+			return true, nil
+	}
+}
@@ -0,0 +1,156 @@
+package hilvlimpl
+
+import (
+	"os"
+	"fmt"
+	"time"
+	herr "hike/error"
+	gen "hike/generic"
+	abs "hike/abstract"
+	con "hike/concrete"
+)
+
+// ---------------------------------------- Step ----------------------------------------
+
+type TouchStampStep struct {
+	con.StepBase
+	Path string
+	Arise *herr.AriseRef
+}
+
+func (step *TouchStampStep) Perform() herr.BuildError {
+	err := con.MakeEnclosingDirectories(step.Path, step.Arise)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	nerr := os.Chtimes(step.Path, now, now)
+	if os.IsNotExist(nerr) {
+		nerr = os.WriteFile(step.Path, nil, 0666)
+	}
+	if nerr != nil {
+		return &gen.WriteFileError {
+			Path: step.Path,
+			OSError: nerr,
+			OperationArise: step.Arise,
+		}
+	}
+	return nil
+}
+
+var _ abs.Step = &TouchStampStep{}
+
+// ---------------------------------------- Artifact ----------------------------------------
+
+type StampArtifact struct {
+	con.ArtifactBase
+	StampPath string
+	GeneratingTransform abs.Transform
+}
+
+func NewStampArtifact(
+	key abs.ArtifactKey,
+	name string,
+	arise *herr.AriseRef,
+	stampPath string,
+	generatingTransform abs.Transform,
+) *StampArtifact {
+	artifact := &StampArtifact {
+		StampPath: stampPath,
+		GeneratingTransform: generatingTransform,
+	}
+	artifact.Key = key
+	artifact.ID = abs.NextArtifactID()
+	artifact.Name = name
+	artifact.Arise = arise
+	return artifact
+}
+
+func (artifact *StampArtifact) DisplayName() string {
+	if len(artifact.Name) > 0 {
+		return artifact.Name
+	} else {
+		return artifact.Key.Unified()
+	}
+}
+
+func (artifact *StampArtifact) PathNames(sink []string) ([]string, herr.BuildError) {
+	return append(sink, artifact.StampPath), nil
+}
+
+func (artifact *StampArtifact) ModTime(arise *herr.AriseRef) (stamp time.Time, err herr.BuildError, missing bool) {
+	info, oserr := os.Stat(artifact.StampPath)
+	if oserr == nil {
+		stamp = info.ModTime()
+	} else {
+		missing = os.IsNotExist(oserr)
+		if !missing {
+			err = &con.CannotStatError {
+				Path: artifact.StampPath,
+				OSError: oserr,
+				OperationArise: arise,
+			}
+		}
+	}
+	return
+}
+
+func (artifact *StampArtifact) EarliestModTime(arise *herr.AriseRef) (time.Time, herr.BuildError, bool) {
+	return artifact.ModTime(arise)
+}
+
+func (artifact *StampArtifact) LatestModTime(arise *herr.AriseRef) (time.Time, herr.BuildError, bool) {
+	return artifact.ModTime(arise)
+}
+
+func (artifact *StampArtifact) Flatten() herr.BuildError {
+	return nil
+}
+
+func (artifact *StampArtifact) Require(plan *abs.Plan, requireArise *herr.AriseRef) (err herr.BuildError) {
+	if plan.AlreadyUpToDate(artifact) {
+		return
+	}
+	stepCount := plan.StepCount()
+	err = artifact.GeneratingTransform.Plan(artifact, plan)
+	if err != nil {
+		err.AddErrorFrame(&con.RequireArtifactFrame {
+			Artifact: artifact,
+		})
+		return
+	}
+	if plan.StepCount() != stepCount {
+		step := &TouchStampStep {
+			Path: artifact.StampPath,
+			Arise: artifact.Arise,
+		}
+		step.Description = fmt.Sprintf("[%s] stamp %s", artifact.Key.Project, artifact.DisplayName())
+		plan.AddStep(step)
+	}
+	plan.BroughtUpToDate(artifact)
+	return
+}
+
+func (artifact *StampArtifact) DumpArtifact(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Out = os.Stdout
+	prn.Level(level)
+	prn.Print("stamp ")
+	con.PrintErrorString(prn, artifact.Key.Unified())
+	prn.Println(" {")
+	prn.Indent(1)
+	con.PrintErrorString(prn, artifact.StampPath)
+	prn.Println()
+	prn.Indent(1)
+	prn.Print("name ")
+	con.PrintErrorString(prn, artifact.Name)
+	prn.Println()
+	prn.Indent(1)
+	artifact.GeneratingTransform.DumpTransform(level + 1)
+	prn.Println()
+	prn.Indent(0)
+	prn.Print("}")
+	return prn.Done()
+}
+
+var _ abs.Artifact = &StampArtifact{}
@@ -0,0 +1,257 @@
+package hilvlimpl
+
+import (
+	"os"
+	"fmt"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"path/filepath"
+	"text/template"
+	herr "hike/error"
+	spc "hike/spec"
+	loc "hike/location"
+	gen "hike/generic"
+	abs "hike/abstract"
+	con "hike/concrete"
+)
+
+// ---------------------------------------- BuildError ----------------------------------------
+
+type UndefinedTemplateVariableError struct {
+	herr.BuildErrorBase
+	Variable string
+	Location *loc.Location
+	OperationArise *herr.AriseRef
+}
+
+func (undefined *UndefinedTemplateVariableError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Printf("Undefined variable '%s' in template at ", undefined.Variable)
+	prn.Location(undefined.Location)
+	prn.Println()
+	prn.Indent(0)
+	prn.Print("in operation ")
+	prn.Arise(undefined.OperationArise, 0)
+	undefined.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (undefined *UndefinedTemplateVariableError) BuildErrorLocation() *loc.Location {
+	return undefined.Location
+}
+
+var _ herr.BuildError = &UndefinedTemplateVariableError{}
+
+type TemplateError struct {
+	herr.BuildErrorBase
+	LibError error
+	Location *loc.Location
+	OperationArise *herr.AriseRef
+}
+
+func (failed *TemplateError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Print("Failed to expand template at ")
+	prn.Location(failed.Location)
+	prn.Println()
+	prn.Indent(0)
+	prn.Print("in operation ")
+	prn.Arise(failed.OperationArise, 0)
+	prn.Println()
+	prn.Indent(0)
+	prn.Printf("with runtime saying: %s", failed.LibError.Error())
+	failed.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (failed *TemplateError) BuildErrorLocation() *loc.Location {
+	return failed.Location
+}
+
+var _ herr.BuildError = &TemplateError{}
+
+// ---------------------------------------- Transform ----------------------------------------
+
+type SubstituteTransform struct {
+	con.SingleTransformBase
+	State *spc.State
+	GoTemplate bool
+	UIBase string
+}
+
+func NewSubstituteTransform(
+	arise *herr.AriseRef,
+	source abs.Artifact,
+	state *spc.State,
+	goTemplate bool,
+	uiBase string,
+) *SubstituteTransform {
+	transform := &SubstituteTransform {
+		State: state,
+		GoTemplate: goTemplate,
+		UIBase: uiBase,
+	}
+	transform.Description = "substitute"
+	transform.Arise = arise
+	transform.Source = source
+	return transform
+}
+
+func (xform *SubstituteTransform) expandVariables(path string, text string) ([]byte, herr.BuildError) {
+	var sink strings.Builder
+	line, column := uint(1), uint(1)
+	advance := func(chunk string) {
+		for _, c := range chunk {
+			if c == '\n' {
+				line++
+				column = 1
+			} else {
+				column++
+			}
+		}
+	}
+	for {
+		start := strings.Index(text, "${")
+		if start < 0 {
+			break
+		}
+		end := spc.FindInterpolationEnd(text, start + 2)
+		if end < 0 {
+			break
+		}
+		sink.WriteString(text[:start])
+		advance(text[:start])
+		location := &loc.Location {
+			File: path,
+			Line: line,
+			Column: column,
+		}
+		expr := text[start + 2:end]
+		whole := text[start:end + 1]
+		colon := strings.IndexRune(expr, ':')
+		if colon < 0 || strings.Contains(expr[:colon], "{") {
+			value, exists := xform.State.VarValue(expr)
+			if !exists {
+				return nil, &UndefinedTemplateVariableError {
+					Variable: expr,
+					Location: location,
+					OperationArise: xform.Arise,
+				}
+			}
+			sink.WriteString(value)
+		} else {
+			value, err := xform.State.InterpolateString(whole, &herr.AriseRef {
+				Text: "template expression",
+				Location: location,
+			})
+			if err != nil {
+				return nil, err
+			}
+			sink.WriteString(value)
+		}
+		advance(whole)
+		text = text[end + 1:]
+	}
+	sink.WriteString(text)
+	return []byte(sink.String()), nil
+}
+
+func (xform *SubstituteTransform) templateError(path string, err error) herr.BuildError {
+	location := &loc.Location {
+		File: path,
+	}
+	prefix := regexp.MustCompile("^template: " + regexp.QuoteMeta(path) + ":([0-9]+)(?::([0-9]+))?:")
+	match := prefix.FindStringSubmatch(err.Error())
+	if match != nil {
+		line, _ := strconv.ParseUint(match[1], 10, 32)
+		location.Line = uint(line)
+		if len(match[2]) > 0 {
+			column, _ := strconv.ParseUint(match[2], 10, 32)
+			location.Column = uint(column)
+		}
+	}
+	return &TemplateError {
+		LibError: err,
+		Location: location,
+		OperationArise: xform.Arise,
+	}
+}
+
+func (xform *SubstituteTransform) expandTemplate(path string, text string) ([]byte, herr.BuildError) {
+	tpl, err := template.New(path).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, xform.templateError(path, err)
+	}
+	var sink bytes.Buffer
+	err = tpl.Execute(&sink, xform.State.Variables())
+	if err != nil {
+		return nil, xform.templateError(path, err)
+	}
+	return sink.Bytes(), nil
+}
+
+func (xform *SubstituteTransform) Render(path string) ([]byte, herr.BuildError) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &gen.ReadFileError {
+			Path: path,
+			OSError: err,
+			OperationArise: xform.Arise,
+		}
+	}
+	if xform.GoTemplate {
+		return xform.expandTemplate(path, string(text))
+	} else {
+		return xform.expandVariables(path, string(text))
+	}
+}
+
+func (xform *SubstituteTransform) Plan(destination abs.Artifact, plan *abs.Plan) herr.BuildError {
+	return gen.PlanContentTransform(
+		xform,
+		[]abs.Artifact{xform.Source},
+		destination,
+		plan,
+		func(dest string) ([]byte, herr.BuildError) {
+			paths, err := xform.Source.PathNames(nil)
+			if err != nil {
+				return nil, err
+			}
+			src, err := con.SinglePath(paths, "substitute " + xform.Source.DisplayName(), false, xform.Arise)
+			if err != nil {
+				return nil, err
+			}
+			return xform.Render(src)
+		},
+		func(dest string) string {
+			return fmt.Sprintf(
+				"substitute %s -> %s",
+				xform.Source.DisplayName(),
+				filepath.ToSlash(con.RelPath(dest, xform.UIBase)),
+			)
+		},
+	)
+}
+
+func (xform *SubstituteTransform) DumpTransform(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Out = os.Stdout
+	prn.Level(level)
+	prn.Println("substitute {")
+	prn.Indent(1)
+	con.PrintErrorString(prn, xform.Source.ArtifactKey().Unified())
+	prn.Println()
+	if xform.GoTemplate {
+		prn.Indent(1)
+		prn.Println("template")
+	}
+	prn.Indent(0)
+	prn.Print("}")
+	return prn.Done()
+}
+
+var _ abs.Transform = &SubstituteTransform{}
@@ -0,0 +1,186 @@
+package hilvlimpl
+
+import (
+	"os"
+	"fmt"
+	"time"
+	"path/filepath"
+	herr "hike/error"
+	abs "hike/abstract"
+	con "hike/concrete"
+	gen "hike/generic"
+)
+
+type SymlinkArtifact struct {
+	con.ArtifactBase
+	Path string
+	Target string
+	TargetArtifact abs.Artifact
+	Relative bool
+	UIBase string
+}
+
+func NewSymlinkArtifact(
+	key abs.ArtifactKey,
+	name string,
+	arise *herr.AriseRef,
+	path string,
+	target string,
+	targetArtifact abs.Artifact,
+	relative bool,
+	uiBase string,
+) *SymlinkArtifact {
+	artifact := &SymlinkArtifact {
+		Path: path,
+		Target: target,
+		TargetArtifact: targetArtifact,
+		Relative: relative,
+		UIBase: uiBase,
+	}
+	artifact.Key = key
+	artifact.ID = abs.NextArtifactID()
+	artifact.Name = name
+	artifact.Arise = arise
+	return artifact
+}
+
+func (artifact *SymlinkArtifact) DisplayName() string {
+	if len(artifact.Name) > 0 {
+		return artifact.Name
+	} else {
+		return artifact.Path
+	}
+}
+
+func (artifact *SymlinkArtifact) PathNames(sink []string) ([]string, herr.BuildError) {
+	return append(sink, artifact.Path), nil
+}
+
+func (artifact *SymlinkArtifact) ModTime(arise *herr.AriseRef) (stamp time.Time, err herr.BuildError, missing bool) {
+	info, oserr := os.Stat(artifact.Path)
+	if os.IsNotExist(oserr) {
+		info, oserr = os.Lstat(artifact.Path)
+	}
+	if oserr == nil {
+		stamp = info.ModTime()
+	} else {
+		missing = os.IsNotExist(oserr)
+		if !missing {
+			err = &con.CannotStatError {
+				Path: artifact.Path,
+				OSError: oserr,
+				OperationArise: arise,
+			}
+		}
+	}
+	return
+}
+
+func (artifact *SymlinkArtifact) EarliestModTime(arise *herr.AriseRef) (time.Time, herr.BuildError, bool) {
+	return artifact.ModTime(arise)
+}
+
+func (artifact *SymlinkArtifact) LatestModTime(arise *herr.AriseRef) (time.Time, herr.BuildError, bool) {
+	return artifact.ModTime(arise)
+}
+
+func (artifact *SymlinkArtifact) Flatten() herr.BuildError {
+	return nil
+}
+
+func (artifact *SymlinkArtifact) DesiredTarget() (string, herr.BuildError) {
+	if artifact.TargetArtifact == nil {
+		return artifact.Target, nil
+	}
+	paths, err := artifact.TargetArtifact.PathNames(nil)
+	if err != nil {
+		return "", err
+	}
+	if len(paths) != 1 {
+		return "", &con.ConflictingDestinationsError {
+			Operation: "link " + artifact.DisplayName(),
+			OperationArise: artifact.Arise,
+			PathCount: uint(len(paths)),
+			PathsAreDestinations: false,
+		}
+	}
+	target, nerr := gen.SymlinkTargetText(paths[0], artifact.Path, artifact.Relative)
+	if nerr != nil {
+		return "", &gen.CreateLinkError {
+			Target: paths[0],
+			Destination: artifact.Path,
+			OSError: nerr,
+			OperationArise: artifact.Arise,
+		}
+	}
+	return target, nil
+}
+
+func (artifact *SymlinkArtifact) Require(plan *abs.Plan, requireArise *herr.AriseRef) (err herr.BuildError) {
+	if plan.AlreadyUpToDate(artifact) {
+		return
+	}
+	if artifact.TargetArtifact != nil {
+		err = artifact.TargetArtifact.Require(plan, requireArise)
+	}
+	var target string
+	if err == nil {
+		target, err = artifact.DesiredTarget()
+	}
+	if err != nil {
+		err.AddErrorFrame(&con.RequireArtifactFrame {
+			Artifact: artifact,
+		})
+		return
+	}
+	current, oserr := os.Readlink(artifact.Path)
+	if oserr != nil || current != target {
+		step := &gen.LinkStep {
+			Target: target,
+			Destination: artifact.Path,
+			Arise: artifact.Arise,
+		}
+		step.Description = fmt.Sprintf(
+			"[%s] link %s -> %s",
+			artifact.Key.Project,
+			filepath.ToSlash(con.RelPath(artifact.Path, artifact.UIBase)),
+			target,
+		)
+		plan.AddStep(step)
+	}
+	plan.BroughtUpToDate(artifact)
+	return
+}
+
+func (artifact *SymlinkArtifact) DumpArtifact(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Out = os.Stdout
+	prn.Level(level)
+	prn.Print("symlinkTo ")
+	con.PrintErrorString(prn, artifact.Key.Unified())
+	prn.Println(" {")
+	prn.Indent(1)
+	con.PrintErrorString(prn, artifact.Path)
+	prn.Println()
+	prn.Indent(1)
+	prn.Print("name ")
+	con.PrintErrorString(prn, artifact.Name)
+	prn.Println()
+	prn.Indent(1)
+	if artifact.TargetArtifact != nil {
+		prn.Print("to ")
+		con.PrintErrorString(prn, artifact.TargetArtifact.ArtifactKey().Unified())
+		if artifact.Relative {
+			prn.Print(" relative")
+		}
+	} else {
+		prn.Print("target ")
+		con.PrintErrorString(prn, artifact.Target)
+	}
+	prn.Println()
+	prn.Indent(0)
+	prn.Print("}")
+	return prn.Done()
+}
+
+var _ abs.Artifact = &SymlinkArtifact{}
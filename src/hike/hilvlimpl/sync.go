@@ -0,0 +1,406 @@
+package hilvlimpl
+
+import (
+	"os"
+	"fmt"
+	"sort"
+	"path/filepath"
+	herr "hike/error"
+	hlv "hike/hilevel"
+	gen "hike/generic"
+	abs "hike/abstract"
+	con "hike/concrete"
+)
+
+// ---------------------------------------- Step ----------------------------------------
+
+type MakeDirectoryStep struct {
+	con.StepBase
+	Path string
+	Arise *herr.AriseRef
+}
+
+func (step *MakeDirectoryStep) Perform() herr.BuildError {
+	err := os.MkdirAll(step.Path, 0755)
+	if err != nil {
+		return &con.CannotCreateDirectoryError {
+			Path: step.Path,
+			OSError: err,
+			OperationArise: step.Arise,
+		}
+	}
+	return nil
+}
+
+var _ abs.Step = &MakeDirectoryStep{}
+
+type PruneDirectoryStep struct {
+	con.StepBase
+	Path string
+	Arise *herr.AriseRef
+}
+
+func (step *PruneDirectoryStep) Perform() herr.BuildError {
+	entries, err := os.ReadDir(step.Path)
+	if err == nil && len(entries) > 0 {
+		return nil
+	}
+	if err == nil {
+		err = os.Remove(step.Path)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return &con.CannotDeleteFileError {
+			Path: step.Path,
+			OSError: err,
+			OperationArise: step.Arise,
+		}
+	}
+	return nil
+}
+
+var _ abs.Step = &PruneDirectoryStep{}
+
+// ---------------------------------------- Transform ----------------------------------------
+
+func SyncNeedsCopy(src string, dest string, preserveSymlinks bool) bool {
+	destInfo, err := os.Lstat(dest)
+	if err != nil {
+		return true
+	}
+	if preserveSymlinks {
+		srcInfo, err := os.Lstat(src)
+		if err != nil {
+			return true
+		}
+		if srcInfo.Mode() & os.ModeSymlink != 0 {
+			if destInfo.Mode() & os.ModeSymlink == 0 {
+				return true
+			}
+			srcTarget, serr := os.Readlink(src)
+			destTarget, derr := os.Readlink(dest)
+			return serr != nil || derr != nil || srcTarget != destTarget
+		}
+	}
+	if destInfo.Mode() & os.ModeSymlink != 0 {
+		return true
+	}
+	srcInfo, err := os.Stat(src)
+	if err != nil || destInfo.IsDir() {
+		return true
+	}
+	return srcInfo.Size() != destInfo.Size() || srcInfo.ModTime().After(destInfo.ModTime())
+}
+
+type SyncTransform struct {
+	con.MultiTransformBase
+	RebaseFrom string
+	PreserveSymlinks bool
+	Excludes []hlv.FileFilter
+	UIBase string
+}
+
+func NewSyncTransform(arise *herr.AriseRef, rebaseFrom string, uiBase string) *SyncTransform {
+	transform := &SyncTransform {
+		RebaseFrom: rebaseFrom,
+		UIBase: uiBase,
+	}
+	transform.Description = "sync"
+	transform.Arise = arise
+	return transform
+}
+
+func (xform *SyncTransform) AddExclude(filter hlv.FileFilter) {
+	xform.Excludes = append(xform.Excludes, filter)
+}
+
+func (xform *SyncTransform) isExcluded(path string, root string, info os.FileInfo) bool {
+	for _, filter := range xform.Excludes {
+		if filter.AcceptFile(path, root, info) {
+			return true
+		}
+	}
+	return false
+}
+
+func (xform *SyncTransform) relName(path string) string {
+	return filepath.ToSlash(con.RelPath(path, xform.UIBase))
+}
+
+type syncEntry struct {
+	source string
+	isDir bool
+}
+
+// syncSteps computes the steps that bring dest in line with the given source
+// paths. Directory sources are synced along with their contents. This must
+// only be called once all sources exist, i.e. at Perform time if any of them
+// are generated.
+func (xform *SyncTransform) syncSteps(dest string, project string, srcPaths []string) ([]abs.Step, herr.BuildError) {
+	entries := make(map[string]*syncEntry)
+	var targets []string
+	addEntry := func(src string, isDir bool) {
+		target := filepath.Join(dest, con.ForceToRelativeAndRebase(src, xform.RebaseFrom))
+		if entries[target] == nil {
+			targets = append(targets, target)
+		}
+		entries[target] = &syncEntry {
+			source: src,
+			isDir: isDir,
+		}
+	}
+	for _, src := range srcPaths {
+		var info os.FileInfo
+		var serr error
+		if xform.PreserveSymlinks {
+			info, serr = os.Lstat(src)
+		} else {
+			info, serr = os.Stat(src)
+		}
+		if serr != nil || !info.IsDir() {
+			addEntry(src, false)
+			continue
+		}
+		werr := filepath.Walk(src, func(path string, info os.FileInfo, inerr error) error {
+			if inerr != nil {
+				return inerr
+			}
+			addEntry(path, info.IsDir())
+			return nil
+		})
+		if werr != nil {
+			return nil, &FSWalkError {
+				RootDir: src,
+				OSError: werr,
+				WalkArise: xform.Arise,
+			}
+		}
+	}
+	expected := make(map[string]bool)
+	for _, target := range targets {
+		expected[target] = entries[target].isDir
+		for dir := filepath.Dir(target); dir != dest && len(dir) > len(dest); dir = filepath.Dir(dir) {
+			expected[dir] = true
+		}
+	}
+	err := PrepareFileFilters(xform.Excludes)
+	if err != nil {
+		return nil, err
+	}
+	var steps []abs.Step
+	var prune []string
+	kept := make(map[string]bool)
+	werr := filepath.Walk(dest, func(path string, info os.FileInfo, inerr error) error {
+		if inerr != nil {
+			if os.IsNotExist(inerr) {
+				return nil
+			}
+			return inerr
+		}
+		if path == dest {
+			return nil
+		}
+		if xform.isExcluded(path, dest, info) {
+			for dir := filepath.Dir(path); len(dir) > len(dest); dir = filepath.Dir(dir) {
+				kept[dir] = true
+			}
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		wantDir, known := expected[path]
+		switch {
+			case known && wantDir == info.IsDir():
+				return nil
+			case !known && info.IsDir():
+				prune = append(prune, path)
+				return nil
+		}
+		step := &gen.DeletePathStep {
+			Path: path,
+			DeleteArise: xform.Arise,
+		}
+		step.Description = fmt.Sprintf("[%s] sync delete %s", project, xform.relName(path))
+		steps = append(steps, step)
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if werr != nil {
+		return nil, &FSWalkError {
+			RootDir: dest,
+			OSError: werr,
+			WalkArise: xform.Arise,
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(prune)))
+	for _, dir := range prune {
+		if kept[dir] {
+			continue
+		}
+		step := &PruneDirectoryStep {
+			Path: dir,
+			Arise: xform.Arise,
+		}
+		step.Description = fmt.Sprintf("[%s] sync delete %s", project, xform.relName(dir))
+		steps = append(steps, step)
+	}
+	for _, target := range targets {
+		entry := entries[target]
+		if expected[target] {
+			info, serr := os.Stat(target)
+			if serr != nil || !info.IsDir() {
+				step := &MakeDirectoryStep {
+					Path: target,
+					Arise: xform.Arise,
+				}
+				step.Description = fmt.Sprintf("[%s] sync mkdir %s", project, xform.relName(target))
+				steps = append(steps, step)
+			}
+			continue
+		}
+		if !SyncNeedsCopy(entry.source, target, xform.PreserveSymlinks) {
+			continue
+		}
+		step := &gen.CopyFileStep {
+			Source: entry.source,
+			Destination: target,
+			PreserveSymlinks: xform.PreserveSymlinks,
+			Arise: xform.Arise,
+		}
+		step.Description = fmt.Sprintf(
+			"[%s] sync copy %s -> %s",
+			project,
+			xform.relName(entry.source),
+			xform.relName(target),
+		)
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func syncDestinationPath(destination abs.Artifact, arise *herr.AriseRef) (string, herr.BuildError) {
+	destPaths, err := destination.PathNames(nil)
+	if err != nil {
+		return "", err
+	}
+	if len(destPaths) != 1 {
+		return "", &con.ConflictingDestinationsError {
+			Operation: "sync files to: " + destination.DisplayName(),
+			OperationArise: arise,
+			PathCount: uint(len(destPaths)),
+			PathsAreDestinations: true,
+		}
+	}
+	return destPaths[0], nil
+}
+
+type SyncStep struct {
+	con.StepBase
+	Transform *SyncTransform
+	Destination abs.Artifact
+}
+
+func (step *SyncStep) Perform() herr.BuildError {
+	dest, err := syncDestinationPath(step.Destination, step.Transform.Arise)
+	if err != nil {
+		return err
+	}
+	srcPaths, err := con.PathsOfArtifacts(step.Transform.Sources)
+	if err != nil {
+		return err
+	}
+	steps, err := step.Transform.syncSteps(dest, step.Destination.ArtifactKey().Project, srcPaths)
+	if err != nil {
+		return err
+	}
+	for _, sub := range steps {
+		err = sub.Perform()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var _ abs.Step = &SyncStep{}
+
+func (xform *SyncTransform) planSync(destination abs.Artifact, plan *abs.Plan) herr.BuildError {
+	dest, err := syncDestinationPath(destination, xform.Arise)
+	if err != nil {
+		return err
+	}
+	project := destination.ArtifactKey().Project
+	stepCount := plan.StepCount()
+	for _, source := range xform.Sources {
+		err = source.Require(plan, xform.Arise)
+		if err != nil {
+			return err
+		}
+	}
+	if plan.StepCount() != stepCount {
+		// Some sources are yet to be generated, so neither their paths nor
+		// their contents are known: defer the whole sync to Perform time.
+		step := &SyncStep {
+			Transform: xform,
+			Destination: destination,
+		}
+		step.Description = fmt.Sprintf("[%s] sync %s", project, xform.relName(dest))
+		plan.AddStep(step)
+		return nil
+	}
+	srcPaths, err := con.PathsOfArtifacts(xform.Sources)
+	if err != nil {
+		return err
+	}
+	steps, err := xform.syncSteps(dest, project, srcPaths)
+	if err != nil {
+		return err
+	}
+	for _, step := range steps {
+		plan.AddStep(step)
+	}
+	return nil
+}
+
+func (xform *SyncTransform) Plan(destination abs.Artifact, plan *abs.Plan) herr.BuildError {
+	err := xform.planSync(destination, plan)
+	if err != nil {
+		err.AddErrorFrame(&con.ApplyTransformFrame {
+			Transform: xform,
+		})
+	}
+	return err
+}
+
+func (xform *SyncTransform) DumpTransform(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Out = os.Stdout
+	prn.Level(level)
+	prn.Println("sync {")
+	for _, source := range xform.Sources {
+		prn.Indent(1)
+		con.PrintErrorString(prn, source.ArtifactKey().Unified())
+		prn.Println()
+	}
+	prn.Indent(1)
+	prn.Print("rebaseFrom ")
+	con.PrintErrorString(prn, xform.RebaseFrom)
+	prn.Println()
+	if xform.PreserveSymlinks {
+		prn.Indent(1)
+		prn.Println("preserveSymlinks")
+	}
+	for _, filter := range xform.Excludes {
+		prn.Indent(1)
+		prn.Print("exclude ")
+		prn.Inject(filter.DumpFilter, level + 1)
+		prn.Println()
+	}
+	prn.Indent(0)
+	prn.Print("}")
+	return prn.Done()
+}
+
+var _ abs.Transform = &SyncTransform{}
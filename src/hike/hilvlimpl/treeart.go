@@ -2,6 +2,7 @@ package hilvlimpl
 
 import (
 	"os"
+	"fmt"
 	"time"
 	"path/filepath"
 	herr "hike/error"
@@ -20,8 +21,14 @@ type TreeArtifact struct {
 	con.ArtifactBase
 	Root string
 	Filters []hlv.FileFilter
+	IgnoreFiles []string
+	FollowSymlinks bool
+	GeneratingTransform abs.Transform
 	cachedPaths []string
 	cacheState int
+	rootMissing bool
+	generating bool
+	pending bool
 	earliestModTime time.Time
 	latestModTime time.Time
 }
@@ -32,11 +39,13 @@ func NewTreeArtifact(
 	arise *herr.AriseRef,
 	root string,
 	filters []hlv.FileFilter,
+	ignoreFiles []string,
 	noCache bool,
 ) *TreeArtifact {
 	artifact := &TreeArtifact {
 		Root: root,
 		Filters: filters,
+		IgnoreFiles: ignoreFiles,
 	}
 	artifact.Key = key
 	artifact.ID = abs.NextArtifactID()
@@ -50,6 +59,69 @@ func NewTreeArtifact(
 	return artifact
 }
 
+type RescanTreeStep struct {
+	con.StepBase
+	Tree *TreeArtifact
+}
+
+func (step *RescanTreeStep) Perform() herr.BuildError {
+	step.Tree.pending = false
+	step.Tree.InvalidateCache()
+	return nil
+}
+
+var _ abs.Step = &RescanTreeStep{}
+
+func walkMapped(
+	realRoot string,
+	shownRoot string,
+	follow bool,
+	ancestors map[string]bool,
+	walkFn filepath.WalkFunc,
+) error {
+	return filepath.Walk(realRoot, func(realPath string, info os.FileInfo, inerr error) error {
+		shownPath := shownRoot
+		if realPath != realRoot {
+			shownPath = filepath.Join(shownRoot, realPath[len(realRoot) + 1:])
+		}
+		if inerr != nil || !follow || info.Mode() & os.ModeSymlink == 0 {
+			return walkFn(shownPath, info, inerr)
+		}
+		targetInfo, serr := os.Stat(realPath)
+		if serr != nil {
+			return walkFn(shownPath, info, nil)
+		}
+		if !targetInfo.IsDir() {
+			return walkFn(shownPath, targetInfo, nil)
+		}
+		resolved, rerr := filepath.EvalSymlinks(realPath)
+		if rerr != nil {
+			return walkFn(shownPath, info, rerr)
+		}
+		if ancestors[resolved] {
+			return nil
+		}
+		ancestors[resolved] = true
+		err := walkMapped(resolved, shownPath, follow, ancestors, walkFn)
+		delete(ancestors, resolved)
+		return err
+	})
+}
+
+func WalkTree(root string, follow bool, walkFn filepath.WalkFunc) error {
+	if !follow {
+		return filepath.Walk(root, walkFn)
+	}
+	resolved, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return filepath.Walk(root, walkFn)
+	}
+	ancestors := map[string]bool {
+		resolved: true,
+	}
+	return walkMapped(resolved, root, follow, ancestors, walkFn)
+}
+
 func (artifact *TreeArtifact) DisplayName() string {
 	if len(artifact.Name) > 0 {
 		return artifact.Name
@@ -58,19 +130,54 @@ func (artifact *TreeArtifact) DisplayName() string {
 	}
 }
 
+func (artifact *TreeArtifact) InvalidateCache() {
+	if artifact.cacheState == TreeArtifactCacheFilled {
+		artifact.cacheState = TreeArtifactCachePending
+	}
+	artifact.cachedPaths = nil
+}
+
 func (artifact *TreeArtifact) fillCache() herr.BuildError {
 	if artifact.cacheState == TreeArtifactCacheFilled {
 		return nil
 	}
+	err := PrepareFileFilters(artifact.Filters)
+	if err != nil {
+		return err
+	}
 	artifact.cachedPaths = nil
+	artifact.rootMissing = false
+	if artifact.GeneratingTransform != nil {
+		_, nerr := os.Stat(artifact.Root)
+		if os.IsNotExist(nerr) {
+			artifact.rootMissing = true
+			if artifact.cacheState == TreeArtifactCachePending {
+				artifact.cacheState = TreeArtifactCacheFilled
+			}
+			return nil
+		}
+	}
 	artifact.earliestModTime = time.Now()
 	artifact.latestModTime = time.Now()
 	var have bool
-	outerr := filepath.Walk(artifact.Root, func(fullPath string, info os.FileInfo, inerr error) error {
+	var ignore *IgnoreMatcher
+	if len(artifact.IgnoreFiles) > 0 {
+		ignore = NewIgnoreMatcher(artifact.Root, artifact.IgnoreFiles)
+	}
+	outerr := WalkTree(artifact.Root, artifact.FollowSymlinks, func(fullPath string, info os.FileInfo, inerr error) error {
 		if inerr != nil {
 			return inerr
 		}
+		if ignore != nil {
+			skip, ierr := ignore.SkipWalkEntry(fullPath, info)
+			if skip {
+				return ierr
+			}
+		}
 		if !AllFileFilters(fullPath, artifact.Root, info, artifact.Filters) {
+			if info.IsDir() && AnyFileFilterPrunes(fullPath, artifact.Root, info, artifact.Filters) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 		mod := info.ModTime()
@@ -87,6 +194,9 @@ func (artifact *TreeArtifact) fillCache() herr.BuildError {
 			}
 		}
 		artifact.cachedPaths = append(artifact.cachedPaths, fullPath)
+		if info.IsDir() && AnyFileFilterPrunes(fullPath, artifact.Root, info, artifact.Filters) {
+			return filepath.SkipDir
+		}
 		return nil
 	})
 	if outerr != nil {
@@ -103,6 +213,9 @@ func (artifact *TreeArtifact) fillCache() herr.BuildError {
 }
 
 func (artifact *TreeArtifact) PathNames(sink []string) ([]string, herr.BuildError) {
+	if artifact.generating || artifact.pending {
+		return append(sink, artifact.Root), nil
+	}
 	err := artifact.fillCache()
 	if err != nil {
 		return nil, err
@@ -114,21 +227,62 @@ func (artifact *TreeArtifact) PathNames(sink []string) ([]string, herr.BuildErro
 }
 
 func (artifact *TreeArtifact) EarliestModTime(arise *herr.AriseRef) (time.Time, herr.BuildError, bool) {
+	if artifact.pending {
+		return time.Now(), nil, true
+	}
 	err := artifact.fillCache()
-	return artifact.earliestModTime, err, false
+	return artifact.earliestModTime, err, artifact.rootMissing
 }
 
 func (artifact *TreeArtifact) LatestModTime(arise *herr.AriseRef) (time.Time, herr.BuildError, bool) {
+	if artifact.pending {
+		return time.Now(), nil, true
+	}
 	err := artifact.fillCache()
-	return artifact.latestModTime, err, false
+	return artifact.latestModTime, err, artifact.rootMissing
 }
 
 func (artifact *TreeArtifact) Flatten() herr.BuildError {
 	return nil
 }
 
-func (artifact *TreeArtifact) Require(plan *abs.Plan, requireArise *herr.AriseRef) herr.BuildError {
-	return nil
+func (artifact *TreeArtifact) addRescanStep(plan *abs.Plan) {
+	step := &RescanTreeStep {
+		Tree: artifact,
+	}
+	step.Description = fmt.Sprintf("[%s] rescan %s", artifact.Key.Project, artifact.DisplayName())
+	plan.AddStep(step)
+}
+
+// While the generating steps are pending, PathNames yields only the root, and
+// each further Require adds a rescan step so that later consumers, too, see
+// new steps and defer resolving the tree's paths to Perform time.
+func (artifact *TreeArtifact) Require(plan *abs.Plan, requireArise *herr.AriseRef) (err herr.BuildError) {
+	if artifact.GeneratingTransform == nil {
+		return
+	}
+	if plan.AlreadyUpToDate(artifact) {
+		if artifact.pending {
+			artifact.addRescanStep(plan)
+		}
+		return
+	}
+	stepCount := plan.StepCount()
+	artifact.generating = true
+	err = artifact.GeneratingTransform.Plan(artifact, plan)
+	artifact.generating = false
+	if err != nil {
+		err.AddErrorFrame(&con.RequireArtifactFrame {
+			Artifact: artifact,
+		})
+		return
+	}
+	if plan.StepCount() != stepCount {
+		artifact.pending = true
+		artifact.addRescanStep(plan)
+	}
+	plan.BroughtUpToDate(artifact)
+	return
 }
 
 func (artifact *TreeArtifact) DumpArtifact(level uint) error {
@@ -151,11 +305,29 @@ func (artifact *TreeArtifact) DumpArtifact(level uint) error {
 		prn.Indent(1)
 		prn.Println("noCache")
 	}
+	if artifact.FollowSymlinks {
+		prn.Indent(1)
+		prn.Println("followSymlinks")
+	}
+	if len(artifact.IgnoreFiles) > 0 {
+		prn.Indent(1)
+		prn.Print("ignoreFiles")
+		for _, ignoreFile := range artifact.IgnoreFiles {
+			prn.Print(" ")
+			con.PrintErrorString(prn, ignoreFile)
+		}
+		prn.Println()
+	}
 	for _, filter := range artifact.Filters {
 		prn.Indent(1)
 		prn.Fail(filter.DumpFilter(level + 1))
 		prn.Println()
 	}
+	if artifact.GeneratingTransform != nil {
+		prn.Indent(1)
+		prn.Fail(artifact.GeneratingTransform.DumpTransform(level + 1))
+		prn.Println()
+	}
 	prn.Indent(0)
 	prn.Print("}")
 	return prn.Done()
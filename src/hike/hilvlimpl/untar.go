@@ -0,0 +1,453 @@
+package hilvlimpl
+
+import (
+	"os"
+	"io"
+	"fmt"
+	"time"
+	"bufio"
+	"bytes"
+	"strings"
+	"archive/tar"
+	"path/filepath"
+	"compress/gzip"
+	"compress/bzip2"
+	herr "hike/error"
+	loc "hike/location"
+	abs "hike/abstract"
+	con "hike/concrete"
+)
+
+// ---------------------------------------- BuildError ----------------------------------------
+
+type ExtractTarError struct {
+	herr.BuildErrorBase
+	Archive string
+	LibError error
+	OperationArise *herr.AriseRef
+}
+
+func (extract *ExtractTarError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Println("Failed to extract tar archive")
+	prn.Indent(1)
+	prn.Println(extract.Archive)
+	prn.Indent(0)
+	prn.Print("in operation ")
+	prn.Arise(extract.OperationArise, 0)
+	prn.Println()
+	prn.Indent(0)
+	prn.Printf("because: %s", extract.LibError.Error())
+	extract.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (extract *ExtractTarError) BuildErrorLocation() *loc.Location {
+	return extract.OperationArise.Location
+}
+
+var _ herr.BuildError = &ExtractTarError{}
+
+type UnsafeArchiveEntryError struct {
+	herr.BuildErrorBase
+	Archive string
+	Entry string
+	Reason string
+	OperationArise *herr.AriseRef
+}
+
+func (unsafe *UnsafeArchiveEntryError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Printf("Refusing to extract entry '%s' from archive", unsafe.Entry)
+	prn.Println()
+	prn.Indent(1)
+	prn.Println(unsafe.Archive)
+	prn.Indent(0)
+	prn.Print("in operation ")
+	prn.Arise(unsafe.OperationArise, 0)
+	prn.Println()
+	prn.Indent(0)
+	prn.Printf("because: %s", unsafe.Reason)
+	unsafe.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (unsafe *UnsafeArchiveEntryError) BuildErrorLocation() *loc.Location {
+	return unsafe.OperationArise.Location
+}
+
+var _ herr.BuildError = &UnsafeArchiveEntryError{}
+
+// ---------------------------------------- Step ----------------------------------------
+
+func OpenTarReader(in io.Reader) (*tar.Reader, error) {
+	brd := bufio.NewReader(in)
+	magic, err := brd.Peek(3)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	switch {
+		case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+			gzr, err := gzip.NewReader(brd)
+			if err != nil {
+				return nil, err
+			}
+			return tar.NewReader(gzr), nil
+		case bytes.HasPrefix(magic, []byte("BZh")):
+			return tar.NewReader(bzip2.NewReader(brd)), nil
+		default:
+			return tar.NewReader(brd), nil
+	}
+}
+
+func IsPathWithin(root string, candidate string) bool {
+	rel, err := filepath.Rel(root, candidate)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".." + string(filepath.Separator))
+}
+
+type UntarStep struct {
+	con.StepBase
+	Archives []abs.Artifact
+	Destination abs.Artifact
+	Valves []*UnzipValve
+	Arise *herr.AriseRef
+}
+
+type untarState struct {
+	archive string
+	extracted map[string]string
+	dirTimes map[string]time.Time
+}
+
+func (step *UntarStep) fail(archive string, err error) herr.BuildError {
+	return &ExtractTarError {
+		Archive: archive,
+		LibError: err,
+		OperationArise: step.Arise,
+	}
+}
+
+func (step *UntarStep) unsafe(archive string, entry string, reason string) herr.BuildError {
+	return &UnsafeArchiveEntryError {
+		Archive: archive,
+		Entry: entry,
+		Reason: reason,
+		OperationArise: step.Arise,
+	}
+}
+
+func (step *UntarStep) checkTarget(state *untarState, entry string, root string, target string) herr.BuildError {
+	if !IsPathWithin(root, target) {
+		return step.unsafe(state.archive, entry, "destination lies outside " + root)
+	}
+	parent, nerr := filepath.EvalSymlinks(filepath.Dir(target))
+	switch {
+		case nerr == nil:
+			realRoot, rerr := filepath.EvalSymlinks(root)
+			if rerr != nil {
+				realRoot = root
+			}
+			if !IsPathWithin(realRoot, parent) {
+				return step.unsafe(state.archive, entry, "enclosing directory is a symbolic link leading outside " + root)
+			}
+		case os.IsNotExist(nerr):
+		default:
+			return step.fail(state.archive, nerr)
+	}
+	return nil
+}
+
+func (step *UntarStep) prepareTarget(state *untarState, target string) herr.BuildError {
+	err := con.MakeEnclosingDirectories(target, step.Arise)
+	if err != nil {
+		return err
+	}
+	info, nerr := os.Lstat(target)
+	switch {
+		case nerr == nil:
+			if !info.IsDir() {
+				nerr = os.Remove(target)
+				if nerr != nil {
+					return step.fail(state.archive, nerr)
+				}
+			}
+		case os.IsNotExist(nerr):
+		default:
+			return step.fail(state.archive, nerr)
+	}
+	return nil
+}
+
+func (step *UntarStep) extractEntry(
+	state *untarState,
+	trd *tar.Reader,
+	header *tar.Header,
+	entryPath string,
+	root string,
+	target string,
+) herr.BuildError {
+	err := step.checkTarget(state, header.Name, root, target)
+	if err != nil {
+		return err
+	}
+	mode := os.FileMode(header.Mode).Perm()
+	switch header.Typeflag {
+		case tar.TypeDir:
+			nerr := os.MkdirAll(target, 0755)
+			if nerr != nil {
+				return step.fail(state.archive, nerr)
+			}
+			nerr = os.Chmod(target, mode | 0700)
+			if nerr != nil {
+				return step.fail(state.archive, nerr)
+			}
+			state.dirTimes[target] = header.ModTime
+		case tar.TypeSymlink:
+			if filepath.IsAbs(header.Linkname) {
+				return step.unsafe(state.archive, header.Name, "symbolic link target is absolute: " + header.Linkname)
+			}
+			resolved := filepath.Join(filepath.Dir(target), filepath.FromSlash(header.Linkname))
+			if !IsPathWithin(root, resolved) {
+				return step.unsafe(state.archive, header.Name, "symbolic link target lies outside " + root)
+			}
+			err = step.prepareTarget(state, target)
+			if err != nil {
+				return err
+			}
+			nerr := os.Symlink(header.Linkname, target)
+			if nerr != nil {
+				return step.fail(state.archive, nerr)
+			}
+		case tar.TypeLink:
+			linkPath, _, _ := SplitArchiveEntryName(header.Linkname)
+			source, present := state.extracted[linkPath]
+			if !present {
+				return step.unsafe(state.archive, header.Name, "hard link target was not extracted: " + header.Linkname)
+			}
+			err = step.prepareTarget(state, target)
+			if err != nil {
+				return err
+			}
+			nerr := os.Link(source, target)
+			if nerr != nil {
+				return step.fail(state.archive, nerr)
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			err = step.prepareTarget(state, target)
+			if err != nil {
+				return err
+			}
+			outf, nerr := os.OpenFile(target, os.O_WRONLY | os.O_CREATE | os.O_TRUNC, 0644)
+			if nerr != nil {
+				return step.fail(state.archive, nerr)
+			}
+			_, nerr = io.Copy(outf, trd)
+			if nerr != nil {
+				outf.Close()
+				return step.fail(state.archive, nerr)
+			}
+			nerr = outf.Close()
+			if nerr != nil {
+				return step.fail(state.archive, nerr)
+			}
+			nerr = os.Chmod(target, mode)
+			if nerr != nil {
+				return step.fail(state.archive, nerr)
+			}
+			nerr = os.Chtimes(target, header.ModTime, header.ModTime)
+			if nerr != nil {
+				return step.fail(state.archive, nerr)
+			}
+		default:
+			return nil
+	}
+	state.extracted[entryPath] = target
+	return nil
+}
+
+func (step *UntarStep) extractArchive(state *untarState, dest string) herr.BuildError {
+	inf, nerr := os.Open(state.archive)
+	if nerr != nil {
+		return step.fail(state.archive, nerr)
+	}
+	defer inf.Close()
+	trd, nerr := OpenTarReader(inf)
+	if nerr != nil {
+		return step.fail(state.archive, nerr)
+	}
+	for {
+		header, nerr := trd.Next()
+		if nerr == io.EOF {
+			break
+		}
+		if nerr != nil {
+			return step.fail(state.archive, nerr)
+		}
+		rawName := filepath.ToSlash(header.Name)
+		if strings.HasPrefix(rawName, "/") {
+			return step.unsafe(state.archive, header.Name, "entry name is absolute")
+		}
+		for _, part := range strings.Split(rawName, "/") {
+			if part == ".." {
+				return step.unsafe(state.archive, header.Name, "entry name refers to parent directory")
+			}
+		}
+		entryPath, basename, dir := SplitArchiveEntryName(rawName)
+		if len(entryPath) == 0 {
+			continue
+		}
+		info := header.FileInfo()
+		for _, valve := range step.Valves {
+			if !valve.MatchesEntry(entryPath, info) {
+				continue
+			}
+			root := dest
+			if filepath.IsAbs(valve.RebaseTo) {
+				root = valve.RebaseTo
+			}
+			target := valve.Destination(dir, basename, dest)
+			err := step.extractEntry(state, trd, header, entryPath, root, target)
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
+
+func (step *UntarStep) Perform() herr.BuildError {
+	destPaths, err := step.Destination.PathNames(nil)
+	if err != nil {
+		return err
+	}
+	if len(destPaths) != 1 {
+		return &con.ConflictingDestinationsError {
+			Operation: "extract tar archives to " + step.Destination.DisplayName(),
+			OperationArise: step.Arise,
+			PathCount: uint(len(destPaths)),
+			PathsAreDestinations: true,
+		}
+	}
+	dest := destPaths[0]
+	archPaths, err := con.PathsOfArtifacts(step.Archives)
+	if err != nil {
+		return err
+	}
+	err = PrepareUnzipValves(step.Valves)
+	if err != nil {
+		return err
+	}
+	for _, archive := range archPaths {
+		state := &untarState {
+			archive: archive,
+			extracted: make(map[string]string),
+			dirTimes: make(map[string]time.Time),
+		}
+		err = step.extractArchive(state, dest)
+		if err != nil {
+			return err
+		}
+		for dir, mtime := range state.dirTimes {
+			nerr := os.Chtimes(dir, mtime, mtime)
+			if nerr != nil {
+				return step.fail(archive, nerr)
+			}
+		}
+	}
+	return nil
+}
+
+var _ abs.Step = &UntarStep{}
+
+// ---------------------------------------- Transform ----------------------------------------
+
+type UntarTransform struct {
+	con.MultiTransformBase
+	Valves []*UnzipValve
+	ArchiveBase string
+}
+
+func NewUntarTransform(
+	description string,
+	arise *herr.AriseRef,
+	archives []abs.Artifact,
+	archiveBase string,
+	valves []*UnzipValve,
+) *UntarTransform {
+	transform := &UntarTransform {
+		Valves: valves,
+		ArchiveBase: archiveBase,
+	}
+	transform.Sources = archives
+	transform.Description = description
+	transform.Arise = arise
+	return transform
+}
+
+func (xform *UntarTransform) AddArchive(archive abs.Artifact) {
+	xform.Sources = append(xform.Sources, archive)
+}
+
+func (xform *UntarTransform) AddValve(valve *UnzipValve) {
+	xform.Valves = append(xform.Valves, valve)
+}
+
+func (xform *UntarTransform) Plan(destination abs.Artifact, plan *abs.Plan) herr.BuildError {
+	return con.PlanMultiTransform(
+		xform,
+		xform.Sources,
+		destination,
+		plan,
+		con.RequireNoMore,
+		func() herr.BuildError {
+			archPaths, err := con.PathsOfArtifacts(xform.Sources)
+			if err != nil {
+				return err
+			}
+			step := &UntarStep {
+				Archives: xform.Sources,
+				Destination: destination,
+				Valves: xform.Valves,
+				Arise: xform.Arise,
+			}
+			step.Description = fmt.Sprintf(
+				"[%s] %s %s",
+				destination.ArtifactKey().Project,
+				xform.Description,
+				con.GuessGroupArtifactName(archPaths, xform.ArchiveBase),
+			)
+			plan.AddStep(step)
+			return nil
+		},
+	)
+}
+
+func (xform *UntarTransform) DumpTransform(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Out = os.Stdout
+	prn.Level(level)
+	prn.Print("untar ")
+	con.PrintErrorString(prn, xform.Description)
+	prn.Print(" {")
+	for _, archive := range xform.Sources {
+		prn.Println()
+		prn.Indent(1)
+		con.PrintErrorString(prn, archive.ArtifactKey().Unified())
+	}
+	for _, valve := range xform.Valves {
+		prn.Println()
+		valve.DumpValve(prn, 1)
+	}
+	prn.Println()
+	prn.Indent(0)
+	prn.Print("}")
+	return prn.Done()
+}
+
+var _ abs.Transform = &UntarTransform{}
@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"strings"
 	"archive/zip"
+	"hash/crc32"
 	"path/filepath"
 	herr "hike/error"
 	hlv "hike/hilevel"
@@ -50,11 +51,42 @@ var _ herr.BuildError = &ExtractZipError{}
 
 // ---------------------------------------- Step ----------------------------------------
 
+type UnzipOptions struct {
+	PreserveModes bool
+	PreserveTimes bool
+	Symlinks bool
+	SkipIdentical bool
+}
+
+func (options *UnzipOptions) DumpOptions(prn *herr.ErrorPrinter, level uint) {
+	if options.PreserveModes {
+		prn.Println()
+		prn.Indent(level)
+		prn.Print("preserveModes")
+	}
+	if options.PreserveTimes {
+		prn.Println()
+		prn.Indent(level)
+		prn.Print("preserveTimes")
+	}
+	if options.Symlinks {
+		prn.Println()
+		prn.Indent(level)
+		prn.Print("symlinks")
+	}
+	if options.SkipIdentical {
+		prn.Println()
+		prn.Indent(level)
+		prn.Print("skipIdentical")
+	}
+}
+
 type UnzipStep struct {
 	con.StepBase
 	Archives []abs.Artifact
 	Destination abs.Artifact
 	Valves []*UnzipValve
+	Options UnzipOptions
 	Arise *herr.AriseRef
 }
 
@@ -66,103 +98,212 @@ func (step *UnzipStep) fail(archive string, err error) herr.BuildError {
 	}
 }
 
-func (step *UnzipStep) Perform() herr.BuildError {
-	destPaths, err := step.Destination.PathNames(nil)
+func (step *UnzipStep) unsafe(archive string, entry string, reason string) herr.BuildError {
+	return &UnsafeArchiveEntryError {
+		Archive: archive,
+		Entry: entry,
+		Reason: reason,
+		OperationArise: step.Arise,
+	}
+}
+
+func FileHasChecksum(path string, size uint64, checksum uint32) bool {
+	info, err := os.Lstat(path)
+	if err != nil || !info.Mode().IsRegular() || uint64(info.Size()) != size {
+		return false
+	}
+	inf, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer inf.Close()
+	hash := crc32.NewIEEE()
+	_, err = io.Copy(hash, inf)
+	return err == nil && hash.Sum32() == checksum
+}
+
+func (step *UnzipStep) extractSymlink(archive string, zfile *zip.File, root string, newPath string) herr.BuildError {
+	zrd, nerr := zfile.Open()
+	if nerr != nil {
+		return step.fail(archive, nerr)
+	}
+	target, nerr := io.ReadAll(zrd)
+	zrd.Close()
+	if nerr != nil {
+		return step.fail(archive, nerr)
+	}
+	linkname := string(target)
+	if filepath.IsAbs(linkname) {
+		return step.unsafe(archive, zfile.Name, "symbolic link target is absolute: " + linkname)
+	}
+	if !IsPathWithin(root, filepath.Join(filepath.Dir(newPath), filepath.FromSlash(linkname))) {
+		return step.unsafe(archive, zfile.Name, "symbolic link target lies outside " + root)
+	}
+	current, nerr := os.Readlink(newPath)
+	if nerr == nil && current == linkname && step.Options.SkipIdentical {
+		return nil
+	}
+	err := con.MakeEnclosingDirectories(newPath, step.Arise)
 	if err != nil {
 		return err
 	}
-	if len(destPaths) != 1 {
-		return &con.ConflictingDestinationsError {
-			Operation: "extract zip archives to " + step.Destination.DisplayName(),
-			OperationArise: step.Arise,
-			PathCount: uint(len(destPaths)),
-			PathsAreDestinations: true,
+	nerr = os.Remove(newPath)
+	if nerr != nil && !os.IsNotExist(nerr) {
+		return step.fail(archive, nerr)
+	}
+	nerr = os.Symlink(linkname, newPath)
+	if nerr != nil {
+		return step.fail(archive, nerr)
+	}
+	return nil
+}
+
+func (step *UnzipStep) extractFile(archive string, zfile *zip.File, newPath string) herr.BuildError {
+	if !step.Options.SkipIdentical || !FileHasChecksum(newPath, zfile.UncompressedSize64, zfile.CRC32) {
+		err := step.writeFile(archive, zfile, newPath)
+		if err != nil {
+			return err
 		}
 	}
-	dest := destPaths[0]
-	archPaths, err := con.PathsOfArtifacts(step.Archives)
-	for _, archive := range archPaths {
-		finfo, nerr := os.Stat(archive)
+	if step.Options.PreserveModes {
+		nerr := os.Chmod(newPath, zfile.Mode().Perm())
 		if nerr != nil {
 			return step.fail(archive, nerr)
 		}
-		inf, nerr := os.Open(archive)
+	}
+	if step.Options.PreserveTimes {
+		nerr := os.Chtimes(newPath, zfile.Modified, zfile.Modified)
 		if nerr != nil {
 			return step.fail(archive, nerr)
 		}
-		zrd, nerr := zip.NewReader(inf, finfo.Size())
-		if nerr != nil {
-			inf.Close()
-			return step.fail(archive, nerr)
+	}
+	return nil
+}
+
+func (step *UnzipStep) writeFile(archive string, zfile *zip.File, newPath string) herr.BuildError {
+	zrd, nerr := zfile.Open()
+	if nerr != nil {
+		return step.fail(archive, nerr)
+	}
+	defer zrd.Close()
+	err := con.MakeEnclosingDirectories(newPath, step.Arise)
+	if err != nil {
+		return err
+	}
+	if step.Options.Symlinks {
+		info, nerr := os.Lstat(newPath)
+		if nerr == nil && info.Mode() & os.ModeSymlink != 0 {
+			nerr = os.Remove(newPath)
+			if nerr != nil {
+				return step.fail(archive, nerr)
+			}
 		}
-		for _, zfile := range zrd.File {
-			fwrap := newUnzippableFile(zfile)
-			for _, valve := range step.Valves {
-				if !valve.Matches(fwrap) {
-					continue
-				}
-				var newBasename, newDir, newPath string
-				if valve.BasenameRegex == nil {
-					newBasename = fwrap.Basename
-				} else {
-					newBasename = valve.BasenameRegex.ReplaceAllString(fwrap.Basename, valve.BasenameReplacement)
-				}
-				if strings.HasPrefix(fwrap.EnclosingDirectory + "/", valve.RebaseFrom + "/") {
-					tail := fwrap.EnclosingDirectory[len(valve.RebaseFrom):]
-					newDir = filepath.Join(valve.RebaseTo, filepath.FromSlash(tail))
-				} else {
-					newDir = filepath.FromSlash(fwrap.EnclosingDirectory)
-				}
-				if filepath.IsAbs(newDir) {
-					newPath = filepath.Join(newDir, newBasename)
-				} else {
-					newPath = filepath.Join(dest, newDir, newBasename)
-				}
-				if fwrap.Directory {
+	}
+	outf, nerr := os.OpenFile(newPath, os.O_WRONLY | os.O_CREATE | os.O_TRUNC, 0644)
+	if nerr != nil {
+		return step.fail(archive, nerr)
+	}
+	_, nerr = io.Copy(outf, zrd)
+	if nerr != nil {
+		outf.Close()
+		return step.fail(archive, nerr)
+	}
+	nerr = outf.Close()
+	if nerr != nil {
+		return step.fail(archive, nerr)
+	}
+	return nil
+}
+
+func (step *UnzipStep) extractArchive(archive string, dest string, dirTimes map[string]time.Time) herr.BuildError {
+	finfo, nerr := os.Stat(archive)
+	if nerr != nil {
+		return step.fail(archive, nerr)
+	}
+	inf, nerr := os.Open(archive)
+	if nerr != nil {
+		return step.fail(archive, nerr)
+	}
+	defer inf.Close()
+	zrd, nerr := zip.NewReader(inf, finfo.Size())
+	if nerr != nil {
+		return step.fail(archive, nerr)
+	}
+	for _, zfile := range zrd.File {
+		fwrap := newUnzippableFile(zfile)
+		for _, valve := range step.Valves {
+			if !valve.Matches(fwrap) {
+				continue
+			}
+			newPath := valve.Destination(fwrap.EnclosingDirectory, fwrap.Basename, dest)
+			var err herr.BuildError
+			switch {
+				case fwrap.Directory:
 					nerr = os.MkdirAll(newPath, 0755)
-					if nerr!= nil {
-						inf.Close()
-						return step.fail(archive, nerr)
-					}
-				} else {
-					zrd, nerr := zfile.Open()
 					if nerr != nil {
-						inf.Close()
 						return step.fail(archive, nerr)
 					}
-					err = con.MakeEnclosingDirectories(newPath, step.Arise)
-					if err != nil {
-						zrd.Close()
-						inf.Close()
-						return err
+					if step.Options.PreserveModes {
+						nerr = os.Chmod(newPath, zfile.Mode().Perm() | 0700)
+						if nerr != nil {
+							return step.fail(archive, nerr)
+						}
 					}
-					outf, nerr := os.OpenFile(newPath, os.O_WRONLY | os.O_CREATE | os.O_TRUNC, 0644)
-					if nerr != nil {
-						zrd.Close()
-						inf.Close()
-						return step.fail(archive, nerr)
+					if step.Options.PreserveTimes {
+						dirTimes[newPath] = zfile.Modified
 					}
-					_, nerr = io.Copy(outf, zrd)
-					if nerr != nil {
-						zrd.Close()
-						inf.Close()
-						return step.fail(archive, nerr)
+				case step.Options.Symlinks && zfile.Mode() & os.ModeSymlink != 0:
+					root := dest
+					if filepath.IsAbs(valve.RebaseTo) {
+						root = valve.RebaseTo
 					}
-					nerr = zrd.Close()
-					if nerr != nil {
-						inf.Close()
-						return step.fail(archive, nerr)
-					}
-					nerr = outf.Close()
-					if nerr != nil {
-						inf.Close()
-						return step.fail(archive, nerr)
-					}
-				}
-				break
+					err = step.extractSymlink(archive, zfile, root, newPath)
+				default:
+					err = step.extractFile(archive, zfile, newPath)
 			}
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
+
+func (step *UnzipStep) Perform() herr.BuildError {
+	destPaths, err := step.Destination.PathNames(nil)
+	if err != nil {
+		return err
+	}
+	if len(destPaths) != 1 {
+		return &con.ConflictingDestinationsError {
+			Operation: "extract zip archives to " + step.Destination.DisplayName(),
+			OperationArise: step.Arise,
+			PathCount: uint(len(destPaths)),
+			PathsAreDestinations: true,
+		}
+	}
+	dest := destPaths[0]
+	archPaths, err := con.PathsOfArtifacts(step.Archives)
+	if err != nil {
+		return err
+	}
+	err = PrepareUnzipValves(step.Valves)
+	if err != nil {
+		return err
+	}
+	dirTimes := make(map[string]time.Time)
+	for _, archive := range archPaths {
+		err = step.extractArchive(archive, dest, dirTimes)
+		if err != nil {
+			return err
+		}
+	}
+	for dir, mtime := range dirTimes {
+		nerr := os.Chtimes(dir, mtime, mtime)
+		if nerr != nil {
+			return step.fail(dir, nerr)
 		}
-		inf.Close()
 	}
 	return nil
 }
@@ -177,17 +318,22 @@ type UnzippableFile struct {
 	Directory bool
 }
 
-func newUnzippableFile(file *zip.File) *UnzippableFile {
-	zfname := filepath.ToSlash(file.Name)
-	zfpath := path.Clean("/" + zfname)[1:]
-	basename := path.Base(zfpath)
+func SplitArchiveEntryName(name string) (entryPath string, basename string, dir string) {
+	entryPath = path.Clean("/" + filepath.ToSlash(name))[1:]
+	basename = path.Base(entryPath)
 	if basename == "." || basename == "/" {
 		basename = ""
 	}
-	dir := path.Dir(zfpath)
+	dir = path.Dir(entryPath)
 	if dir == "." || dir == "/" {
 		dir = ""
 	}
+	return
+}
+
+func newUnzippableFile(file *zip.File) *UnzippableFile {
+	zfname := filepath.ToSlash(file.Name)
+	zfpath, basename, dir := SplitArchiveEntryName(zfname)
 	return &UnzippableFile {
 		File: file,
 		Path: zfpath,
@@ -206,11 +352,7 @@ func (file *UnzippableFile) Size() int64 {
 }
 
 func (file *UnzippableFile) Mode() os.FileMode {
-	if file.Directory {
-		return 0755
-	} else {
-		return 0644
-	}
+	return file.File.Mode()
 }
 
 func (file *UnzippableFile) ModTime() time.Time {
@@ -240,19 +382,93 @@ func (valve *UnzipValve) AddFilter(filter hlv.FileFilter) {
 	valve.Filters = append(valve.Filters, filter)
 }
 
+func PrepareUnzipValves(valves []*UnzipValve) herr.BuildError {
+	for _, valve := range valves {
+		err := PrepareFileFilters(valve.Filters)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (valve *UnzipValve) Matches(file *UnzippableFile) bool {
+	return valve.MatchesEntry(file.Path, file)
+}
+
+func (valve *UnzipValve) MatchesEntry(entryPath string, info os.FileInfo) bool {
 	switch {
-		case len(file.Path) <= len(valve.RebaseFrom):
+		case len(entryPath) <= len(valve.RebaseFrom):
 			return false
-		case len(valve.RebaseFrom) > 0 && !strings.HasPrefix(file.Path, valve.RebaseFrom + "/"):
+		case len(valve.RebaseFrom) > 0 && !strings.HasPrefix(entryPath, valve.RebaseFrom + "/"):
 			return false
 	}
-	return AllFileFilters(filepath.FromSlash(file.Path), "", file, valve.Filters)
+	return AllFileFilters(filepath.FromSlash(entryPath), "", info, valve.Filters)
+}
+
+func (valve *UnzipValve) Destination(entryDir string, entryBasename string, dest string) string {
+	var newBasename, newDir string
+	if valve.BasenameRegex == nil {
+		newBasename = entryBasename
+	} else {
+		newBasename = valve.BasenameRegex.ReplaceAllString(entryBasename, valve.BasenameReplacement)
+	}
+	if strings.HasPrefix(entryDir + "/", valve.RebaseFrom + "/") {
+		tail := entryDir[len(valve.RebaseFrom):]
+		newDir = filepath.Join(valve.RebaseTo, filepath.FromSlash(tail))
+	} else {
+		newDir = filepath.FromSlash(entryDir)
+	}
+	if filepath.IsAbs(newDir) {
+		return filepath.Join(newDir, newBasename)
+	} else {
+		return filepath.Join(dest, newDir, newBasename)
+	}
+}
+
+func (valve *UnzipValve) DumpValve(prn *herr.ErrorPrinter, level uint) {
+	prn.Indent(level)
+	prn.Print("valve {")
+	haveOpts := false
+	if len(valve.RebaseFrom) > 0 {
+		prn.Println()
+		prn.Indent(level + 1)
+		prn.Print("from ")
+		con.PrintErrorString(prn, valve.RebaseFrom)
+		haveOpts = true
+	}
+	if len(valve.RebaseTo) > 0 {
+		prn.Println()
+		prn.Indent(level + 1)
+		prn.Print("to ")
+		con.PrintErrorString(prn, valve.RebaseTo)
+		haveOpts = true
+	}
+	if valve.BasenameRegex != nil {
+		prn.Println()
+		prn.Indent(level + 1)
+		prn.Print("rename ")
+		con.PrintErrorString(prn, valve.BasenameRegexText)
+		prn.Print(" ")
+		con.PrintErrorString(prn, valve.BasenameReplacement)
+		haveOpts = true
+	}
+	for _, filter := range valve.Filters {
+		prn.Println()
+		prn.Indent(level + 1)
+		prn.Inject(filter.DumpFilter, level + 1)
+	}
+	if haveOpts {
+		prn.Println()
+		prn.Indent(level)
+	}
+	prn.Print("}")
 }
 
 type UnzipTransform struct {
 	con.MultiTransformBase
 	Valves []*UnzipValve
+	Options UnzipOptions
 	ArchiveBase string
 }
 
@@ -297,6 +513,7 @@ func (xform *UnzipTransform) Plan(destination abs.Artifact, plan *abs.Plan) herr
 				Archives: xform.Sources,
 				Destination: destination,
 				Valves: xform.Valves,
+				Options: xform.Options,
 				Arise: xform.Arise,
 			}
 			step.Description = fmt.Sprintf(
@@ -318,6 +535,7 @@ func (xform *UnzipTransform) DumpTransform(level uint) error {
 	prn.Print("unzip ")
 	con.PrintErrorString(prn, xform.Description)
 	prn.Print(" {")
+	xform.Options.DumpOptions(prn, 1)
 	for _, archive := range xform.Sources {
 		prn.Println()
 		prn.Indent(1)
@@ -325,42 +543,7 @@ func (xform *UnzipTransform) DumpTransform(level uint) error {
 	}
 	for _, valve := range xform.Valves {
 		prn.Println()
-		prn.Indent(1)
-		prn.Print("valve {")
-		haveOpts := false
-		if len(valve.RebaseFrom) > 0 {
-			prn.Println()
-			prn.Indent(2)
-			prn.Print("from ")
-			con.PrintErrorString(prn, valve.RebaseFrom)
-			haveOpts = true
-		}
-		if len(valve.RebaseTo) > 0 {
-			prn.Println()
-			prn.Indent(2)
-			prn.Print("to ")
-			con.PrintErrorString(prn, valve.RebaseTo)
-			haveOpts = true
-		}
-		if valve.BasenameRegex != nil {
-			prn.Println()
-			prn.Indent(2)
-			prn.Print("rename ")
-			con.PrintErrorString(prn, valve.BasenameRegexText)
-			prn.Print(" ")
-			con.PrintErrorString(prn, valve.BasenameReplacement)
-			haveOpts = true
-		}
-		for _, filter := range valve.Filters {
-			prn.Println()
-			prn.Indent(2)
-			prn.Inject(filter.DumpFilter, 2)
-		}
-		if haveOpts {
-			prn.Println()
-			prn.Indent(1)
-		}
-		prn.Print("}")
+		valve.DumpValve(prn, 1)
 	}
 	prn.Println()
 	prn.Indent(0)
@@ -13,6 +13,7 @@ func RegisterCoreStructures(known *prs.KnownStructures) {
 	known.RegisterTopParser("setdef", syn.TopSetVarDef)
 	known.RegisterTopParser("include", syn.ParseInclude)
 	known.RegisterTopParser("projectName", syn.ParseProjectName)
+	known.RegisterTopParser("depend", syn.TopDepend)
 	// ActionParser
 	known.RegisterActionParser("attain", syn.TopAttainAction)
 	known.RegisterActionParser("require", syn.TopRequireAction)
@@ -25,15 +26,27 @@ func RegisterCoreStructures(known *prs.KnownStructures) {
 	known.RegisterArtifactParser("pipeline", syn.ParsePipelineArtifact)
 	known.RegisterArtifactParser("tree", syn.TopTreeArtifact)
 	known.RegisterArtifactParser("split", syn.TopSplitArtifact)
+	known.RegisterArtifactParser("archiveEntry", syn.TopArchiveEntryArtifact)
+	known.RegisterArtifactParser("symlinkTo", syn.TopSymlinkArtifact)
+	known.RegisterArtifactParser("stamp", syn.TopStampArtifact)
 	// TransformParser
 	known.RegisterTransformParser("exec", syn.TopCommandTransform)
 	known.RegisterTransformParser("copy", syn.TopCopyTransform)
 	known.RegisterTransformParser("zip", syn.TopZipTransform)
+	known.RegisterTransformParser("tar", syn.TopTarTransform)
 	known.RegisterTransformParser("unzip", syn.TopUnzipTransform)
+	known.RegisterTransformParser("untar", syn.TopUntarTransform)
+	known.RegisterTransformParser("link", syn.TopLinkTransform)
+	known.RegisterTransformParser("substitute", syn.TopSubstituteTransform)
+	known.RegisterTransformParser("concat", syn.TopConcatTransform)
+	known.RegisterTransformParser("write", syn.TopWriteTransform)
+	known.RegisterTransformParser("checksums", syn.TopChecksumsTransform)
+	known.RegisterTransformParser("sync", syn.TopSyncTransform)
 	known.RegisterTransformParser("mkdir", syn.TopMkdirTransform)
 	// ArtifactSetParser
 	known.RegisterArtifactSetParser("each", syn.ParseArtifactEach)
 	known.RegisterArtifactSetParser("scandir", syn.ParseArtifactScanDir)
+	known.RegisterArtifactSetParser("archiveScan", syn.ParseArtifactArchiveScan)
 	// ArtifactFactoryParser
 	known.RegisterArtifactFactoryParser("file", syn.TopStaticFile)
 	known.RegisterArtifactFactoryParser("regex", syn.TopRegexFile)
@@ -44,9 +57,20 @@ func RegisterCoreStructures(known *prs.KnownStructures) {
 	known.RegisterFileFilterParser("files", syn.TopFilesFileFilter)
 	known.RegisterFileFilterParser("directories", syn.TopDirectoriesFileFilter)
 	known.RegisterFileFilterParser("wildcard", syn.TopWildcardFileFilter)
+	known.RegisterFileFilterParser("glob", syn.TopGlobFileFilter)
+	known.RegisterFileFilterParser("regex", syn.TopRegexFileFilter)
+	known.RegisterFileFilterParser("size", syn.TopSizeFileFilter)
+	known.RegisterFileFilterParser("olderThan", syn.TopOlderThanFileFilter)
+	known.RegisterFileFilterParser("newerThan", syn.TopNewerThanFileFilter)
+	known.RegisterFileFilterParser("executable", syn.TopModeFileFilter)
+	known.RegisterFileFilterParser("symlink", syn.TopModeFileFilter)
+	known.RegisterFileFilterParser("empty", syn.TopModeFileFilter)
 	known.RegisterFileFilterParser("all", syn.TopAllFileFilter)
 	known.RegisterFileFilterParser("any", syn.TopAnyFileFilter)
 	known.RegisterFileFilterParser("not", syn.TopNotFileFilter)
+	// RepositoryParser
+	known.RegisterRepositoryParser("local", syn.TopLocalRepository)
+	known.RegisterRepositoryParser("git", syn.TopGitRepository)
 }
 
 func RegisterAllKnownStructures(known *prs.KnownStructures) {
@@ -25,6 +25,11 @@ const (
 	s_STRING_HEX
 	s_STRING_UNICODE16
 	s_STRING_UNICODE32
+	s_RAW_STRING
+	s_LESS
+	s_HEREDOC_TAG
+	s_HEREDOC_HEAD
+	s_HEREDOC_BODY
 )
 
 type Lexer struct {
@@ -33,7 +38,10 @@ type Lexer struct {
 	line uint
 	column uint
 	start uint
+	startLine uint
 	buffer strings.Builder
+	heredocTag string
+	heredocLines []string
 	sink chan *tok.Token
 	firstError herr.BuildError
 	code uint32
@@ -165,7 +173,7 @@ func (lexer *Lexer) emitWithText(ttype tok.Type, text string) {
 	lexer.sink <- &tok.Token {
 		Location: loc.Location {
 			File: lexer.file,
-			Line: lexer.line,
+			Line: lexer.startLine,
 			Column: lexer.start,
 		},
 		Type: ttype,
@@ -173,6 +181,65 @@ func (lexer *Lexer) emitWithText(ttype tok.Type, text string) {
 	}
 }
 
+func (lexer *Lexer) emitRawFromBuffer() {
+	text := lexer.buffer.String()
+	lexer.buffer.Reset()
+	lexer.sink <- &tok.Token {
+		Location: loc.Location {
+			File: lexer.file,
+			Line: lexer.startLine,
+			Column: lexer.start,
+		},
+		Type: tok.T_STRING,
+		Text: text,
+		Raw: true,
+	}
+}
+
+func leadingBlanks(line string) string {
+	return line[:len(line) - len(strings.TrimLeft(line, " \t"))]
+}
+
+func StripCommonIndent(lines []string) string {
+	var indent string
+	haveIndent := false
+	for _, line := range lines {
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		blanks := leadingBlanks(line)
+		switch {
+			case !haveIndent:
+				indent = blanks
+				haveIndent = true
+			default:
+				for !strings.HasPrefix(blanks, indent) {
+					indent = indent[:len(indent) - 1]
+				}
+		}
+	}
+	var sink strings.Builder
+	for _, line := range lines {
+		if len(strings.TrimSpace(line)) > 0 {
+			sink.WriteString(line[len(indent):])
+		}
+		sink.WriteRune('\n')
+	}
+	return sink.String()
+}
+
+func (lexer *Lexer) endHeredocLine() bool {
+	line := strings.TrimRight(lexer.buffer.String(), "\r")
+	lexer.buffer.Reset()
+	if strings.TrimSpace(line) == lexer.heredocTag {
+		lexer.emitWithText(tok.T_STRING, StripCommonIndent(lexer.heredocLines))
+		lexer.heredocLines = nil
+		return true
+	}
+	lexer.heredocLines = append(lexer.heredocLines, line)
+	return false
+}
+
 func (lexer *Lexer) emitFromBuffer(ttype tok.Type) {
 	text := lexer.buffer.String()
 	lexer.buffer.Reset()
@@ -218,6 +285,7 @@ func (lexer *Lexer) PushRune(c rune) {
 	switch lexer.state {
 		case s_NONE:
 			lexer.start = lexer.column
+			lexer.startLine = lexer.line
 			switch c {
 				case ' ', '\t', '\r', '\n':
 				case '{':
@@ -230,6 +298,10 @@ func (lexer *Lexer) PushRune(c rune) {
 					lexer.state = s_PLUS
 				case '"':
 					lexer.state = s_STRING
+				case '`':
+					lexer.state = s_RAW_STRING
+				case '<':
+					lexer.state = s_LESS
 				default:
 					switch {
 						case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
@@ -366,6 +438,69 @@ func (lexer *Lexer) PushRune(c rune) {
 			if lexer.doStringHex(c, 8) {
 				return
 			}
+		case s_RAW_STRING:
+			switch c {
+				case '`':
+					lexer.state = s_NONE
+					lexer.emitRawFromBuffer()
+				case '\r':
+				default:
+					_, err = lexer.buffer.WriteRune(c)
+					if err != nil {
+						lexer.buferr(err)
+						return
+					}
+			}
+		case s_LESS:
+			if c != '<' {
+				lexer.die(c, "'<'")
+				return
+			}
+			lexer.state = s_HEREDOC_TAG
+		case s_HEREDOC_TAG:
+			switch {
+				case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_':
+					_, err = lexer.buffer.WriteRune(c)
+					if err != nil {
+						lexer.buferr(err)
+						return
+					}
+				case lexer.buffer.Len() == 0:
+					lexer.die(c, "heredoc tag")
+					return
+				case c == ' ', c == '\t', c == '\r', c == '\n':
+					lexer.heredocTag = lexer.buffer.String()
+					lexer.buffer.Reset()
+					if c == '\n' {
+						lexer.state = s_HEREDOC_BODY
+					} else {
+						lexer.state = s_HEREDOC_HEAD
+					}
+				default:
+					lexer.die(c, "heredoc tag character or end of line")
+					return
+			}
+		case s_HEREDOC_HEAD:
+			switch c {
+				case ' ', '\t', '\r':
+				case '\n':
+					lexer.state = s_HEREDOC_BODY
+				default:
+					lexer.die(c, "end of line")
+					return
+			}
+		case s_HEREDOC_BODY:
+			if c == '\n' {
+				if lexer.endHeredocLine() {
+					lexer.state = s_NONE
+				}
+			} else {
+				_, err = lexer.buffer.WriteRune(c)
+				if err != nil {
+					lexer.buferr(err)
+					return
+				}
+			}
 		default:
 			panic(fmt.Sprintf("Unrecognized lexer state: %d", uint(lexer.state)))
 	}
@@ -392,6 +527,18 @@ func (lexer *Lexer) EndUnit() herr.BuildError {
 			lexer.noEnd("escape sequence")
 		case s_STRING_HEX, s_STRING_UNICODE16, s_STRING_UNICODE32:
 			lexer.noEnd("hexadecimal digit")
+		case s_RAW_STRING:
+			lexer.noEnd("'`'")
+		case s_LESS:
+			lexer.noEnd("'<'")
+		case s_HEREDOC_TAG:
+			lexer.noEnd("heredoc tag")
+		case s_HEREDOC_HEAD:
+			lexer.noEnd("end of line")
+		case s_HEREDOC_BODY:
+			if !lexer.endHeredocLine() {
+				lexer.noEnd(fmt.Sprintf("heredoc terminator '%s'", lexer.heredocTag))
+			}
 		default:
 			panic(fmt.Sprintf("Unrecognized lexer state: %d", uint(lexer.state)))
 	}
@@ -399,6 +546,7 @@ func (lexer *Lexer) EndUnit() herr.BuildError {
 		lexer.state = s_NONE
 	}
 	lexer.start = lexer.column
+	lexer.startLine = lexer.line
 	lexer.emitWithText(tok.T_EOF, "")
 	return lexer.firstError
 }
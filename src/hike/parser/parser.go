@@ -81,6 +81,7 @@ type TransformFactoryParser func(parser *Parser) hlv.TransformFactory
 type ArtifactFactoryParser func(parser *Parser) hlv.ArtifactFactory
 type ArtifactSetParser func(parser *Parser) []abs.Artifact
 type FileFilterParser func(parser *Parser) hlv.FileFilter
+type RepositoryParser func(parser *Parser) spc.Repository
 
 type KnownStructures struct {
 	top map[string]TopParser
@@ -91,6 +92,7 @@ type KnownStructures struct {
 	artifactFactory map[string]ArtifactFactoryParser
 	artifactSet map[string]ArtifactSetParser
 	fileFilter map[string]FileFilterParser
+	repository map[string]RepositoryParser
 }
 
 func NewKnownStructures() *KnownStructures {
@@ -103,6 +105,7 @@ func NewKnownStructures() *KnownStructures {
 		artifactFactory: make(map[string]ArtifactFactoryParser),
 		artifactSet: make(map[string]ArtifactSetParser),
 		fileFilter: make(map[string]FileFilterParser),
+		repository: make(map[string]RepositoryParser),
 	}
 }
 
@@ -170,6 +173,14 @@ func (known *KnownStructures) FileFilterParser(initiator string) FileFilterParse
 	return known.fileFilter[initiator]
 }
 
+func (known *KnownStructures) RegisterRepositoryParser(initiator string, parser RepositoryParser) {
+	known.repository[initiator] = parser
+}
+
+func (known *KnownStructures) RepositoryParser(initiator string) RepositoryParser {
+	return known.repository[initiator]
+}
+
 func New(
 	lexer chan *tok.Token,
 	knownStructures *KnownStructures,
@@ -187,6 +198,10 @@ func (parser *Parser) SpecState() *spc.State {
 	return parser.specState
 }
 
+func (parser *Parser) KnownStructures() *KnownStructures {
+	return parser.knownStructures
+}
+
 func (parser *Parser) Next() {
 	if parser.Token.Type != tok.T_EOF {
 		parser.Token = <-parser.lexer
@@ -245,7 +260,18 @@ func (parser *Parser) IsKeyword(name string) bool {
 }
 
 func (parser *Parser) InterpolateString() string {
-	return parser.specState.InterpolateString(parser.Token.Text)
+	if parser.Token.Raw {
+		return parser.Token.Text
+	}
+	result, err := parser.specState.InterpolateString(parser.Token.Text, &herr.AriseRef {
+		Text: "string literal",
+		Location: &parser.Token.Location,
+	})
+	if err != nil {
+		parser.Fail(err)
+		return parser.Token.Text
+	}
+	return result
 }
 
 func (parser *Parser) Fail(fault herr.BuildError) {
@@ -436,6 +462,23 @@ func (parser *Parser) IsFileFilter() bool {
 	return parser.Token.Type == tok.T_NAME && parser.knownStructures.FileFilterParser(parser.Token.Text) != nil
 }
 
+func (parser *Parser) Repository() spc.Repository {
+	if !parser.Expect(tok.T_NAME) {
+		return nil
+	}
+	cb := parser.knownStructures.RepositoryParser(parser.Token.Text)
+	if cb == nil {
+		parser.Die("repository")
+		return nil
+	} else {
+		return cb(parser)
+	}
+}
+
+func (parser *Parser) IsRepository() bool {
+	return parser.Token.Type == tok.T_NAME && parser.knownStructures.RepositoryParser(parser.Token.Text) != nil
+}
+
 // ---------------------------------------- intrinsics ----------------------------------------
 
 func (parser *Parser) Utterance() {
@@ -466,7 +509,10 @@ type PendingArtifactRef struct {
 
 func (ref *PendingArtifactRef) InjectArtifact(specState *spc.State, injector func(abs.Artifact)) {
 	specState.SlateResolver(func() herr.BuildError {
-		artifact := specState.Artifact(ref.Key)
+		artifact, err := specState.ResolveArtifact(ref.Key)
+		if err != nil {
+			return err
+		}
 		if artifact != nil {
 			injector(artifact)
 			return nil
@@ -521,7 +567,11 @@ func (parser *Parser) ArtifactRef(arise *herr.AriseRef, extended bool) ArtifactR
 			key := SplitArtifactKey(parser.InterpolateString(), specState.Config)
 			refLocation := &parser.Token.Location
 			parser.Next()
-			artifact := specState.Artifact(key)
+			artifact, err := specState.ResolveArtifact(key)
+			if err != nil {
+				parser.Fail(err)
+				return nil
+			}
 			if artifact != nil {
 				return &PresentArtifactRef {
 					Artifact: artifact,
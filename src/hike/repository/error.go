@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"strings"
+	herr "hike/error"
+	loc "hike/location"
+)
+
+// ---------------------------------------- RepositoryIOError ----------------------------------------
+
+type RepositoryIOError struct {
+	herr.BuildErrorBase
+	Path string
+	OSError error
+	RepositoryArise *herr.AriseRef
+}
+
+func (ioerr *RepositoryIOError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Println("Failed to access repository directory")
+	prn.Indent(1)
+	prn.Println(ioerr.Path)
+	prn.Indent(0)
+	prn.Print("for repository ")
+	prn.Arise(ioerr.RepositoryArise, 0)
+	prn.Println()
+	prn.Indent(0)
+	prn.Printf("because: %s", ioerr.OSError.Error())
+	ioerr.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (ioerr *RepositoryIOError) BuildErrorLocation() *loc.Location {
+	return ioerr.RepositoryArise.Location
+}
+
+var _ herr.BuildError = &RepositoryIOError{}
+
+// ---------------------------------------- GitCommandError ----------------------------------------
+
+type GitCommandError struct {
+	herr.BuildErrorBase
+	Argv []string
+	Fault error
+	Output []byte
+	RepositoryArise *herr.AriseRef
+}
+
+func (failed *GitCommandError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Println("Git command")
+	prn.Indent(1)
+	prn.Println(strings.Join(failed.Argv, " "))
+	prn.Indent(0)
+	prn.Printf("failed: %s\n", failed.Fault.Error())
+	prn.Indent(0)
+	prn.Print("for repository ")
+	prn.Arise(failed.RepositoryArise, 0)
+	output := strings.TrimSpace(string(failed.Output))
+	if len(output) > 0 {
+		prn.Println()
+		prn.Indent(0)
+		prn.Print("Output:")
+		for _, line := range strings.Split(output, "\n") {
+			prn.Println()
+			prn.Indent(1)
+			prn.Print(line)
+		}
+	}
+	failed.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (failed *GitCommandError) BuildErrorLocation() *loc.Location {
+	return failed.RepositoryArise.Location
+}
+
+var _ herr.BuildError = &GitCommandError{}
+
+// ---------------------------------------- NoSuchVersionError ----------------------------------------
+
+type NoSuchVersionError struct {
+	herr.BuildErrorBase
+	Repository string
+	Version string
+	Available []string
+	RepositoryArise *herr.AriseRef
+}
+
+func (no *NoSuchVersionError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	if len(no.Version) > 0 {
+		prn.Printf("No version matching '%s' in %s", no.Version, no.Repository)
+	} else {
+		prn.Printf("No versions at all in %s", no.Repository)
+	}
+	prn.Println()
+	prn.Indent(1)
+	prn.Print("repository ")
+	prn.Arise(no.RepositoryArise, 1)
+	if len(no.Available) > 0 {
+		prn.Println()
+		prn.Indent(1)
+		prn.Print("available versions: ", strings.Join(no.Available, ", "))
+	}
+	no.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (no *NoSuchVersionError) BuildErrorLocation() *loc.Location {
+	return no.RepositoryArise.Location
+}
+
+var _ herr.BuildError = &NoSuchVersionError{}
+
+// ---------------------------------------- IllegalVersionSpecifierError ----------------------------------------
+
+type IllegalVersionSpecifierError struct {
+	herr.BuildErrorBase
+	Specifier string
+	Reason string
+	SpecifierArise *herr.AriseRef
+}
+
+func (illegal *IllegalVersionSpecifierError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Printf("Illegal version specifier '%s': %s", illegal.Specifier, illegal.Reason)
+	prn.Println()
+	prn.Indent(1)
+	prn.Print("specifier ")
+	prn.Arise(illegal.SpecifierArise, 1)
+	illegal.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (illegal *IllegalVersionSpecifierError) BuildErrorLocation() *loc.Location {
+	return illegal.SpecifierArise.Location
+}
+
+var _ herr.BuildError = &IllegalVersionSpecifierError{}
+
+// ---------------------------------------- LockfileIOError ----------------------------------------
+
+type LockfileIOError struct {
+	herr.BuildErrorBase
+	Path string
+	OSError error
+}
+
+func (ioerr *LockfileIOError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Println("Failed to access lockfile")
+	prn.Indent(1)
+	prn.Println(ioerr.Path)
+	prn.Indent(0)
+	prn.Printf("because: %s", ioerr.OSError.Error())
+	ioerr.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (ioerr *LockfileIOError) BuildErrorLocation() *loc.Location {
+	return &loc.Location {
+		File: ioerr.Path,
+	}
+}
+
+var _ herr.BuildError = &LockfileIOError{}
+
+// ---------------------------------------- LockfileSyntaxError ----------------------------------------
+
+type LockfileSyntaxError struct {
+	herr.BuildErrorBase
+	Reason string
+	Location *loc.Location
+}
+
+func (syntax *LockfileSyntaxError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Print("Malformed lockfile entry at ")
+	prn.Location(syntax.Location)
+	prn.Printf(": %s", syntax.Reason)
+	syntax.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (syntax *LockfileSyntaxError) BuildErrorLocation() *loc.Location {
+	return syntax.Location
+}
+
+var _ herr.BuildError = &LockfileSyntaxError{}
+
+// ---------------------------------------- LockDigestMismatchError ----------------------------------------
+
+type LockDigestMismatchError struct {
+	herr.BuildErrorBase
+	DependKey string
+	Version string
+	LockedDigest string
+	ActualDigest string
+	DependArise *herr.AriseRef
+}
+
+func (mismatch *LockDigestMismatchError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Printf("Content of dependency '%s' version '%s' does not match lockfile", mismatch.DependKey, mismatch.Version)
+	prn.Println()
+	prn.Indent(1)
+	prn.Println("locked digest:", mismatch.LockedDigest)
+	prn.Indent(1)
+	prn.Println("actual digest:", mismatch.ActualDigest)
+	prn.Indent(1)
+	prn.Print("dependency ")
+	prn.Arise(mismatch.DependArise, 1)
+	mismatch.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (mismatch *LockDigestMismatchError) BuildErrorLocation() *loc.Location {
+	return mismatch.DependArise.Location
+}
+
+var _ herr.BuildError = &LockDigestMismatchError{}
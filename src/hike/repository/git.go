@@ -0,0 +1,367 @@
+package repository
+
+import (
+	"os"
+	"sort"
+	"bytes"
+	"regexp"
+	"os/exec"
+	"strings"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	herr "hike/error"
+	spc "hike/spec"
+)
+
+func IsRemoteGitURL(url string) bool {
+	if strings.Contains(url, "://") {
+		return true
+	}
+	colon := strings.IndexByte(url, ':')
+	slash := strings.IndexByte(url, '/')
+	return colon > 1 && (slash < 0 || colon < slash)
+}
+
+// ---------------------------------------- GitVersion ----------------------------------------
+
+type GitVersion struct {
+	Name string
+	Commit string
+}
+
+func (version *GitVersion) VersionString() string {
+	return version.Name
+}
+
+func (version *GitVersion) VersionType() string {
+	return "git"
+}
+
+func (version *GitVersion) CompareToVersion(other spc.VersionRef) (int, error) {
+	if other.VersionType() != version.VersionType() {
+		return spc.VRCMP_INCOMPARABLE, nil
+	}
+	if version.Name == other.VersionString() {
+		return spc.VRCMP_EQUAL, nil
+	}
+	left, err := ParseSemVer(version.Name)
+	if err != nil {
+		return spc.VRCMP_INCOMPARABLE, nil
+	}
+	right, err := ParseSemVer(other.VersionString())
+	if err != nil {
+		return spc.VRCMP_INCOMPARABLE, nil
+	}
+	return left.Compare(right), nil
+}
+
+var _ spc.VersionRef = &GitVersion{}
+
+// ---------------------------------------- GitRepository ----------------------------------------
+
+type GitRepository struct {
+	URL string
+	Arise *herr.AriseRef
+	fetched bool
+	commits map[string]string
+}
+
+func NewGitRepository(url string, arise *herr.AriseRef) *GitRepository {
+	return &GitRepository {
+		URL: url,
+		Arise: arise,
+		commits: make(map[string]string),
+	}
+}
+
+func (repo *GitRepository) RepoDescription() string {
+	return "git repository '" + repo.URL + "'"
+}
+
+func (repo *GitRepository) CacheRoot() (string, herr.BuildError) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", &RepositoryIOError {
+			Path: "user cache directory",
+			OSError: err,
+			RepositoryArise: repo.Arise,
+		}
+	}
+	digest := sha256.Sum256([]byte(repo.URL))
+	return filepath.Join(cacheDir, "hike", "git", hex.EncodeToString(digest[:8])), nil
+}
+
+func (repo *GitRepository) mirrorPath() (string, herr.BuildError) {
+	root, err := repo.CacheRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, "mirror.git"), nil
+}
+
+func (repo *GitRepository) runGit(args ...string) (string, herr.BuildError) {
+	argv := append([]string{"git"}, args...)
+	cmd := exec.Command(argv[0])
+	cmd.Args = argv
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", &GitCommandError {
+			Argv: argv,
+			Fault: err,
+			Output: stderr.Bytes(),
+			RepositoryArise: repo.Arise,
+		}
+	}
+	return string(out), nil
+}
+
+func (repo *GitRepository) ensureMirror() (string, herr.BuildError) {
+	mirror, err := repo.mirrorPath()
+	if err != nil {
+		return "", err
+	}
+	_, nerr := os.Stat(mirror)
+	switch {
+		case nerr == nil:
+			return mirror, nil
+		case os.IsNotExist(nerr):
+			nerr = os.MkdirAll(filepath.Dir(mirror), 0777)
+			if nerr != nil {
+				return "", &RepositoryIOError {
+					Path: filepath.Dir(mirror),
+					OSError: nerr,
+					RepositoryArise: repo.Arise,
+				}
+			}
+			_, err = repo.runGit("clone", "--quiet", "--mirror", repo.URL, mirror)
+			if err != nil {
+				return "", err
+			}
+			repo.fetched = true
+			return mirror, nil
+		default:
+			return "", &RepositoryIOError {
+				Path: mirror,
+				OSError: nerr,
+				RepositoryArise: repo.Arise,
+			}
+	}
+}
+
+func (repo *GitRepository) prepareMirror() (string, herr.BuildError) {
+	mirror, err := repo.ensureMirror()
+	if err != nil || repo.fetched {
+		return mirror, err
+	}
+	_, err = repo.runGit("--git-dir", mirror, "fetch", "--quiet", "--prune", "--tags", "origin")
+	if err != nil {
+		return "", err
+	}
+	repo.fetched = true
+	return mirror, nil
+}
+
+var fullCommitHashRegex = regexp.MustCompile("^[0-9a-fA-F]{40}$")
+
+func (repo *GitRepository) resolveCached(mirror string, name string) (string, bool) {
+	var rev string
+	if fullCommitHashRegex.MatchString(name) {
+		rev = name
+	} else {
+		rev = "refs/tags/" + name
+	}
+	out, err := repo.runGit("--git-dir", mirror, "rev-parse", "--verify", "--quiet", rev + "^{commit}")
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(out), true
+}
+
+func (repo *GitRepository) Tags() ([]string, herr.BuildError) {
+	mirror, err := repo.prepareMirror()
+	if err != nil {
+		return nil, err
+	}
+	out, err := repo.runGit("--git-dir", mirror, "tag", "--list")
+	if err != nil {
+		return nil, err
+	}
+	tags := strings.Fields(out)
+	sort.SliceStable(tags, func(i, j int) bool {
+		left, lerr := ParseSemVer(tags[i])
+		right, rerr := ParseSemVer(tags[j])
+		switch {
+			case lerr == nil && rerr == nil:
+				return left.Compare(right) == spc.VRCMP_LESS
+			case lerr == nil || rerr == nil:
+				return rerr == nil
+			default:
+				return tags[i] < tags[j]
+		}
+	})
+	return tags, nil
+}
+
+func (repo *GitRepository) AvailableVersions() ([]spc.VersionRef, herr.BuildError) {
+	tags, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	var versions []spc.VersionRef
+	for _, tag := range tags {
+		versions = append(versions, &GitVersion {
+			Name: tag,
+		})
+	}
+	return versions, nil
+}
+
+func (repo *GitRepository) InternVersion(specifier string, arise *herr.AriseRef) (spc.VersionRef, herr.BuildError) {
+	if strings.HasPrefix(specifier, "-") {
+		return nil, &IllegalVersionSpecifierError {
+			Specifier: specifier,
+			Reason: "must not start with '-'",
+			SpecifierArise: arise,
+		}
+	}
+	name := specifier
+	var commit string
+	cached := false
+	at := strings.LastIndexByte(name, '@')
+	switch {
+		case at > 0 && fullCommitHashRegex.MatchString(name[at + 1:]):
+			name, commit = name[:at], name[at + 1:]
+			mirror, err := repo.ensureMirror()
+			if err != nil {
+				return nil, err
+			}
+			_, cached = repo.resolveCached(mirror, commit)
+			if !cached {
+				mirror, err = repo.prepareMirror()
+				if err != nil {
+					return nil, err
+				}
+				_, cached = repo.resolveCached(mirror, commit)
+			}
+			if !cached {
+				return nil, &NoSuchVersionError {
+					Repository: repo.RepoDescription(),
+					Version: specifier,
+					RepositoryArise: repo.Arise,
+				}
+			}
+		case len(name) > 0:
+			mirror, err := repo.ensureMirror()
+			if err != nil {
+				return nil, err
+			}
+			commit, cached = repo.resolveCached(mirror, name)
+	}
+	if !cached {
+		mirror, err := repo.prepareMirror()
+		if err != nil {
+			return nil, err
+		}
+		tags, err := repo.Tags()
+		if err != nil {
+			return nil, err
+		}
+		if len(name) == 0 {
+			name = "HEAD"
+			for index := len(tags) - 1; index >= 0; index-- {
+				semver, serr := ParseSemVer(tags[index])
+				if serr == nil && len(semver.Prerelease) == 0 {
+					name = tags[index]
+					break
+				}
+			}
+		}
+		out, err := repo.runGit("--git-dir", mirror, "rev-parse", "--verify", "--quiet", name + "^{commit}")
+		if err != nil {
+			return nil, &NoSuchVersionError {
+				Repository: repo.RepoDescription(),
+				Version: name,
+				Available: tags,
+				RepositoryArise: repo.Arise,
+			}
+		}
+		commit = strings.TrimSpace(out)
+	}
+	repo.commits[name] = commit
+	return &GitVersion {
+		Name: name,
+		Commit: commit,
+	}, nil
+}
+
+func (repo *GitRepository) AcquireSnapshot(version spc.VersionRef) (spc.Snapshot, herr.BuildError) {
+	commit, present := repo.commits[version.VersionString()]
+	if !present {
+		interned, err := repo.InternVersion(version.VersionString(), repo.Arise)
+		if err != nil {
+			return nil, err
+		}
+		version = interned
+		commit = repo.commits[version.VersionString()]
+	}
+	mirror, err := repo.ensureMirror()
+	if err != nil {
+		return nil, err
+	}
+	worktree := filepath.Join(filepath.Dir(mirror), "trees", commit)
+	_, nerr := os.Stat(worktree)
+	switch {
+		case nerr == nil:
+		case os.IsNotExist(nerr):
+			_, err = repo.runGit("--git-dir", mirror, "worktree", "prune")
+			if err != nil {
+				return nil, err
+			}
+			_, err = repo.runGit("--git-dir", mirror, "worktree", "add", "--quiet", "--detach", worktree, commit)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, &RepositoryIOError {
+				Path: worktree,
+				OSError: nerr,
+				RepositoryArise: repo.Arise,
+			}
+	}
+	return &GitSnapshot {
+		DirectorySnapshot: NewDirectorySnapshot(repo, version, worktree, repo.Arise),
+		GitRepository: repo,
+		Commit: commit,
+	}, nil
+}
+
+var _ spc.Repository = &GitRepository{}
+
+// ---------------------------------------- GitSnapshot ----------------------------------------
+
+type GitSnapshot struct {
+	*DirectorySnapshot
+	GitRepository *GitRepository
+	Commit string
+}
+
+func (snapshot *GitSnapshot) SnapshotDigest() (string, herr.BuildError) {
+	mirror, err := snapshot.GitRepository.mirrorPath()
+	if err != nil {
+		return "", err
+	}
+	out, err := snapshot.GitRepository.runGit("--git-dir", mirror, "rev-parse", snapshot.Commit + "^{tree}")
+	if err != nil {
+		return "", err
+	}
+	return "git-tree:" + strings.TrimSpace(out), nil
+}
+
+func (snapshot *GitSnapshot) SnapshotLockName() string {
+	return snapshot.Version.VersionString() + "@" + snapshot.Commit
+}
+
+var _ spc.Snapshot = &GitSnapshot{}
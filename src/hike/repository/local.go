@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"strconv"
+	"path/filepath"
+	herr "hike/error"
+	spc "hike/spec"
+)
+
+// ---------------------------------------- version ordering ----------------------------------------
+
+func splitVersionString(version string) []string {
+	return strings.FieldsFunc(version, func(c rune) bool {
+		return c == '.' || c == '-' || c == '_' || c == '+'
+	})
+}
+
+func CompareVersionStrings(left string, right string) int {
+	leftParts := splitVersionString(left)
+	rightParts := splitVersionString(right)
+	for index := 0; index < len(leftParts) && index < len(rightParts); index++ {
+		leftNum, leftErr := strconv.ParseUint(leftParts[index], 10, 64)
+		rightNum, rightErr := strconv.ParseUint(rightParts[index], 10, 64)
+		switch {
+			case leftErr == nil && rightErr == nil:
+				switch {
+					case leftNum < rightNum:
+						return spc.VRCMP_LESS
+					case leftNum > rightNum:
+						return spc.VRCMP_GREATER
+				}
+			case leftErr == nil:
+				return spc.VRCMP_GREATER
+			case rightErr == nil:
+				return spc.VRCMP_LESS
+			case leftParts[index] < rightParts[index]:
+				return spc.VRCMP_LESS
+			case leftParts[index] > rightParts[index]:
+				return spc.VRCMP_GREATER
+		}
+	}
+	switch {
+		case len(leftParts) < len(rightParts):
+			return spc.VRCMP_LESS
+		case len(leftParts) > len(rightParts):
+			return spc.VRCMP_GREATER
+		default:
+			return spc.VRCMP_EQUAL
+	}
+}
+
+// ---------------------------------------- LocalVersion ----------------------------------------
+
+type LocalVersion struct {
+	Name string
+}
+
+func (version *LocalVersion) VersionString() string {
+	return version.Name
+}
+
+func (version *LocalVersion) VersionType() string {
+	return "local"
+}
+
+func (version *LocalVersion) CompareToVersion(other spc.VersionRef) (int, error) {
+	if other.VersionType() != version.VersionType() {
+		return spc.VRCMP_INCOMPARABLE, nil
+	}
+	return CompareVersionStrings(version.Name, other.VersionString()), nil
+}
+
+var _ spc.VersionRef = &LocalVersion{}
+
+// ---------------------------------------- LocalRepository ----------------------------------------
+
+type LocalRepository struct {
+	Path string
+	Arise *herr.AriseRef
+}
+
+func NewLocalRepository(path string, arise *herr.AriseRef) *LocalRepository {
+	return &LocalRepository {
+		Path: path,
+		Arise: arise,
+	}
+}
+
+func (repo *LocalRepository) RepoDescription() string {
+	return "local repository '" + repo.Path + "'"
+}
+
+func (repo *LocalRepository) versionNames() ([]string, herr.BuildError) {
+	dir, err := os.Open(repo.Path)
+	if err != nil {
+		return nil, &RepositoryIOError {
+			Path: repo.Path,
+			OSError: err,
+			RepositoryArise: repo.Arise,
+		}
+	}
+	defer dir.Close()
+	infos, err := dir.Readdir(-1)
+	if err != nil {
+		return nil, &RepositoryIOError {
+			Path: repo.Path,
+			OSError: err,
+			RepositoryArise: repo.Arise,
+		}
+	}
+	var versions []string
+	for _, info := range infos {
+		if info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
+			versions = append(versions, info.Name())
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return CompareVersionStrings(versions[i], versions[j]) == spc.VRCMP_LESS
+	})
+	return versions, nil
+}
+
+func (repo *LocalRepository) AvailableVersions() ([]spc.VersionRef, herr.BuildError) {
+	names, err := repo.versionNames()
+	if err != nil {
+		return nil, err
+	}
+	var versions []spc.VersionRef
+	for _, name := range names {
+		versions = append(versions, InternVersionName(name))
+	}
+	return versions, nil
+}
+
+func (repo *LocalRepository) InternVersion(specifier string, arise *herr.AriseRef) (spc.VersionRef, herr.BuildError) {
+	var reason string
+	switch {
+		case strings.ContainsAny(specifier, "/\\"):
+			reason = "must not contain path separators"
+		case specifier == "." || specifier == "..":
+			reason = "must not refer to a parent or the repository itself"
+		case strings.HasPrefix(specifier, "."):
+			reason = "must not start with '.'"
+		case len(specifier) == 0:
+			return &LocalVersion{}, nil
+		default:
+			return InternVersionName(specifier), nil
+	}
+	return nil, &IllegalVersionSpecifierError {
+		Specifier: specifier,
+		Reason: reason,
+		SpecifierArise: arise,
+	}
+}
+
+func (repo *LocalRepository) AcquireSnapshot(version spc.VersionRef) (spc.Snapshot, herr.BuildError) {
+	available, err := repo.versionNames()
+	if err != nil {
+		return nil, err
+	}
+	name := version.VersionString()
+	if len(name) == 0 {
+		if len(available) > 0 {
+			name = available[len(available) - 1]
+			version = InternVersionName(name)
+		}
+	} else {
+		found := false
+		for _, candidate := range available {
+			if candidate == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			name = ""
+		}
+	}
+	if len(name) == 0 {
+		return nil, &NoSuchVersionError {
+			Repository: repo.RepoDescription(),
+			Version: version.VersionString(),
+			Available: available,
+			RepositoryArise: repo.Arise,
+		}
+	}
+	return NewDirectorySnapshot(repo, version, filepath.Join(repo.Path, name), repo.Arise), nil
+}
+
+var _ spc.Repository = &LocalRepository{}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"os"
+	"sort"
+	"bufio"
+	"strings"
+	herr "hike/error"
+	spc "hike/spec"
+	loc "hike/location"
+)
+
+const LOCKFILE_NAME = "hike.lock"
+
+const lockfileHeader = "# Resolved sub-project dependencies; re-resolve with -update-lock or -update-lock-key.\n"
+
+type LockEntry struct {
+	DependKey string
+	Repository string
+	Version string
+	Digest string
+}
+
+func (entry *LockEntry) sameAs(other *LockEntry) bool {
+	return entry.Repository == other.Repository && entry.Version == other.Version && entry.Digest == other.Digest
+}
+
+// ---------------------------------------- Lockfile ----------------------------------------
+
+type Lockfile struct {
+	Path string
+	entries map[string]*LockEntry
+	used map[string]*LockEntry
+	updateAll bool
+	updateKeys map[string]bool
+}
+
+func ReadLockfile(path string) (*Lockfile, herr.BuildError) {
+	lock := &Lockfile {
+		Path: path,
+		entries: make(map[string]*LockEntry),
+		used: make(map[string]*LockEntry),
+		updateKeys: make(map[string]bool),
+	}
+	file, nerr := os.Open(path)
+	if nerr != nil {
+		if os.IsNotExist(nerr) {
+			return lock, nil
+		}
+		return nil, &LockfileIOError {
+			Path: path,
+			OSError: nerr,
+		}
+	}
+	defer file.Close()
+	scan := bufio.NewScanner(file)
+	var line uint
+	for scan.Scan() {
+		line++
+		text := strings.TrimRight(scan.Text(), "\r")
+		if len(strings.TrimSpace(text)) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) != 4 {
+			return nil, &LockfileSyntaxError {
+				Reason: "expected 4 tab-separated fields",
+				Location: &loc.Location {
+					File: path,
+					Line: line,
+					Column: 1,
+				},
+			}
+		}
+		lock.entries[fields[0]] = &LockEntry {
+			DependKey: fields[0],
+			Repository: fields[1],
+			Version: fields[2],
+			Digest: fields[3],
+		}
+	}
+	nerr = scan.Err()
+	if nerr != nil {
+		return nil, &LockfileIOError {
+			Path: path,
+			OSError: nerr,
+		}
+	}
+	return lock, nil
+}
+
+func (lock *Lockfile) UpdateAll() {
+	lock.updateAll = true
+}
+
+func (lock *Lockfile) UpdateKey(dependKey string) {
+	lock.updateKeys[dependKey] = true
+}
+
+func (lock *Lockfile) LockedVersion(dependKey string, repository spc.Repository) (string, bool) {
+	if lock.updateAll || lock.updateKeys[dependKey] {
+		return "", false
+	}
+	entry := lock.entries[dependKey]
+	if entry == nil || entry.Repository != repository.RepoDescription() {
+		return "", false
+	}
+	return entry.Version, true
+}
+
+func (lock *Lockfile) VerifySnapshot(dependKey string, snapshot spc.Snapshot, arise *herr.AriseRef) herr.BuildError {
+	entry := lock.entries[dependKey]
+	digest, err := snapshot.SnapshotDigest()
+	if err != nil {
+		return err
+	}
+	if digest != entry.Digest {
+		return &LockDigestMismatchError {
+			DependKey: dependKey,
+			Version: entry.Version,
+			LockedDigest: entry.Digest,
+			ActualDigest: digest,
+			DependArise: arise,
+		}
+	}
+	lock.used[dependKey] = entry
+	return nil
+}
+
+func (lock *Lockfile) RecordSnapshot(dependKey string, snapshot spc.Snapshot, arise *herr.AriseRef) herr.BuildError {
+	digest, err := snapshot.SnapshotDigest()
+	if err != nil {
+		return err
+	}
+	lock.used[dependKey] = &LockEntry {
+		DependKey: dependKey,
+		Repository: snapshot.OriginRepo().RepoDescription(),
+		Version: snapshot.SnapshotLockName(),
+		Digest: digest,
+	}
+	return nil
+}
+
+func (lock *Lockfile) Changed() bool {
+	if len(lock.used) != len(lock.entries) {
+		return true
+	}
+	for dependKey, entry := range lock.used {
+		old := lock.entries[dependKey]
+		if old == nil || !old.sameAs(entry) {
+			return true
+		}
+	}
+	return false
+}
+
+func (lock *Lockfile) Write() herr.BuildError {
+	if !lock.Changed() {
+		return nil
+	}
+	if len(lock.used) == 0 {
+		nerr := os.Remove(lock.Path)
+		if nerr != nil && !os.IsNotExist(nerr) {
+			return &LockfileIOError {
+				Path: lock.Path,
+				OSError: nerr,
+			}
+		}
+		return nil
+	}
+	var keys []string
+	for dependKey := range lock.used {
+		keys = append(keys, dependKey)
+	}
+	sort.Strings(keys)
+	var sink strings.Builder
+	sink.WriteString(lockfileHeader)
+	for _, dependKey := range keys {
+		entry := lock.used[dependKey]
+		sink.WriteString(strings.Join([]string {
+			entry.DependKey,
+			entry.Repository,
+			entry.Version,
+			entry.Digest,
+		}, "\t"))
+		sink.WriteByte('\n')
+	}
+	tmpPath := lock.Path + ".tmp"
+	nerr := os.WriteFile(tmpPath, []byte(sink.String()), 0666)
+	if nerr == nil {
+		nerr = os.Rename(tmpPath, lock.Path)
+	}
+	if nerr != nil {
+		return &LockfileIOError {
+			Path: lock.Path,
+			OSError: nerr,
+		}
+	}
+	lock.entries = lock.used
+	return nil
+}
+
+var _ spc.VersionLock = &Lockfile{}
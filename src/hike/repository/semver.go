@@ -0,0 +1,400 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"strconv"
+	spc "hike/spec"
+)
+
+// ---------------------------------------- SemVer ----------------------------------------
+
+type SemVer struct {
+	Major uint64
+	Minor uint64
+	Patch uint64
+	Prerelease []string
+	Build string
+}
+
+func ParseSemVer(text string) (*SemVer, error) {
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "v"), "V")
+	version := &SemVer{}
+	if plus := strings.IndexByte(text, '+'); plus >= 0 {
+		version.Build = text[plus + 1:]
+		text = text[:plus]
+	}
+	if dash := strings.IndexByte(text, '-'); dash >= 0 {
+		version.Prerelease = strings.Split(text[dash + 1:], ".")
+		for _, identifier := range version.Prerelease {
+			if len(identifier) == 0 {
+				return nil, errors.New("empty pre-release identifier")
+			}
+		}
+		text = text[:dash]
+	}
+	parts := strings.Split(text, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("expected MAJOR.MINOR.PATCH")
+	}
+	numbers := []*uint64{&version.Major, &version.Minor, &version.Patch}
+	for index, part := range parts {
+		number, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		*numbers[index] = number
+	}
+	return version, nil
+}
+
+func compareUint(left uint64, right uint64) int {
+	switch {
+		case left < right:
+			return spc.VRCMP_LESS
+		case left > right:
+			return spc.VRCMP_GREATER
+		default:
+			return spc.VRCMP_EQUAL
+	}
+}
+
+func comparePrerelease(left []string, right []string) int {
+	switch {
+		case len(left) == 0 && len(right) == 0:
+			return spc.VRCMP_EQUAL
+		case len(left) == 0:
+			return spc.VRCMP_GREATER
+		case len(right) == 0:
+			return spc.VRCMP_LESS
+	}
+	for index := 0; index < len(left) && index < len(right); index++ {
+		leftNum, leftErr := strconv.ParseUint(left[index], 10, 64)
+		rightNum, rightErr := strconv.ParseUint(right[index], 10, 64)
+		switch {
+			case leftErr == nil && rightErr == nil:
+				if leftNum != rightNum {
+					return compareUint(leftNum, rightNum)
+				}
+			case leftErr == nil:
+				return spc.VRCMP_LESS
+			case rightErr == nil:
+				return spc.VRCMP_GREATER
+			case left[index] < right[index]:
+				return spc.VRCMP_LESS
+			case left[index] > right[index]:
+				return spc.VRCMP_GREATER
+		}
+	}
+	return compareUint(uint64(len(left)), uint64(len(right)))
+}
+
+func (version *SemVer) Compare(other *SemVer) int {
+	switch {
+		case version.Major != other.Major:
+			return compareUint(version.Major, other.Major)
+		case version.Minor != other.Minor:
+			return compareUint(version.Minor, other.Minor)
+		case version.Patch != other.Patch:
+			return compareUint(version.Patch, other.Patch)
+		default:
+			return comparePrerelease(version.Prerelease, other.Prerelease)
+	}
+}
+
+func (version *SemVer) String() string {
+	var sink strings.Builder
+	sink.WriteString(strconv.FormatUint(version.Major, 10))
+	sink.WriteByte('.')
+	sink.WriteString(strconv.FormatUint(version.Minor, 10))
+	sink.WriteByte('.')
+	sink.WriteString(strconv.FormatUint(version.Patch, 10))
+	if len(version.Prerelease) > 0 {
+		sink.WriteByte('-')
+		sink.WriteString(strings.Join(version.Prerelease, "."))
+	}
+	if len(version.Build) > 0 {
+		sink.WriteByte('+')
+		sink.WriteString(version.Build)
+	}
+	return sink.String()
+}
+
+// ---------------------------------------- SemVerVersion ----------------------------------------
+
+type SemVerVersion struct {
+	Name string
+	SemVer *SemVer
+}
+
+func InternVersionName(name string) spc.VersionRef {
+	semver, err := ParseSemVer(name)
+	if err != nil {
+		return &LocalVersion {
+			Name: name,
+		}
+	}
+	return &SemVerVersion {
+		Name: name,
+		SemVer: semver,
+	}
+}
+
+func (version *SemVerVersion) VersionString() string {
+	return version.Name
+}
+
+func (version *SemVerVersion) VersionType() string {
+	return "semver"
+}
+
+func (version *SemVerVersion) CompareToVersion(other spc.VersionRef) (int, error) {
+	semver, err := ParseSemVer(other.VersionString())
+	if err != nil {
+		return spc.VRCMP_INCOMPARABLE, nil
+	}
+	return version.SemVer.Compare(semver), nil
+}
+
+var _ spc.VersionRef = &SemVerVersion{}
+
+// ---------------------------------------- VersionRange ----------------------------------------
+
+type versionBound struct {
+	Operator string
+	Version *SemVer
+}
+
+func (bound *versionBound) admits(version *SemVer) bool {
+	cmp := version.Compare(bound.Version)
+	switch bound.Operator {
+		case ">=":
+			return cmp != spc.VRCMP_LESS
+		case ">":
+			return cmp == spc.VRCMP_GREATER
+		case "<=":
+			return cmp != spc.VRCMP_GREATER
+		case "<":
+			return cmp == spc.VRCMP_LESS
+		default:
+			return cmp == spc.VRCMP_EQUAL
+	}
+}
+
+type VersionRange struct {
+	Specifier string
+	bounds []*versionBound
+	allowPrerelease bool
+}
+
+func IsVersionRange(specifier string) bool {
+	return strings.ContainsAny(specifier, "^~<>=* \t")
+}
+
+func parsePartialVersion(text string) (*SemVer, int, error) {
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "v"), "V")
+	var prerelease string
+	if dash := strings.IndexByte(text, '-'); dash >= 0 {
+		prerelease = text[dash:]
+		text = text[:dash]
+	}
+	parts := strings.Split(text, ".")
+	if len(parts) > 3 {
+		return nil, 0, errors.New("too many version components")
+	}
+	given := len(parts)
+	for len(parts) > 0 {
+		last := parts[len(parts) - 1]
+		if last != "*" && last != "x" && last != "X" {
+			break
+		}
+		parts = parts[:len(parts) - 1]
+		given--
+	}
+	for len(parts) < 3 {
+		parts = append(parts, "0")
+	}
+	if given < 3 && len(prerelease) > 0 {
+		return nil, 0, errors.New("pre-release requires MAJOR.MINOR.PATCH")
+	}
+	version, err := ParseSemVer(strings.Join(parts, ".") + prerelease)
+	if err != nil {
+		return nil, 0, err
+	}
+	return version, given, nil
+}
+
+func bumpVersion(version *SemVer, component int) *SemVer {
+	switch component {
+		case 0:
+			return &SemVer{Major: version.Major + 1}
+		case 1:
+			return &SemVer{Major: version.Major, Minor: version.Minor + 1}
+		default:
+			return &SemVer{Major: version.Major, Minor: version.Minor, Patch: version.Patch + 1}
+	}
+}
+
+func ParseVersionRange(specifier string) (*VersionRange, error) {
+	vrange := &VersionRange {
+		Specifier: specifier,
+	}
+	for _, term := range strings.Fields(specifier) {
+		operator := ""
+		for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+			if strings.HasPrefix(term, prefix) {
+				operator = prefix
+				term = term[len(prefix):]
+				break
+			}
+		}
+		if len(term) == 0 {
+			return nil, errors.New("missing version after '" + operator + "'")
+		}
+		version, given, err := parsePartialVersion(term)
+		if err != nil {
+			return nil, err
+		}
+		if len(version.Prerelease) > 0 {
+			vrange.allowPrerelease = true
+		}
+		lower := &versionBound{Operator: ">=", Version: version}
+		switch {
+			case given == 0:
+			case operator == "^":
+				var component int
+				switch {
+					case version.Major > 0 || given == 1:
+						component = 0
+					case version.Minor > 0 || given == 2:
+						component = 1
+					default:
+						component = 2
+				}
+				vrange.bounds = append(vrange.bounds, lower, &versionBound {
+					Operator: "<",
+					Version: bumpVersion(version, component),
+				})
+			case operator == "~":
+				component := 1
+				if given == 1 {
+					component = 0
+				}
+				vrange.bounds = append(vrange.bounds, lower, &versionBound {
+					Operator: "<",
+					Version: bumpVersion(version, component),
+				})
+			case given < 3 && (operator == "" || operator == "="):
+				vrange.bounds = append(vrange.bounds, lower, &versionBound {
+					Operator: "<",
+					Version: bumpVersion(version, given - 1),
+				})
+			case given < 3 && operator == ">":
+				vrange.bounds = append(vrange.bounds, &versionBound {
+					Operator: ">=",
+					Version: bumpVersion(version, given - 1),
+				})
+			case given < 3 && operator == "<=":
+				vrange.bounds = append(vrange.bounds, &versionBound {
+					Operator: "<",
+					Version: bumpVersion(version, given - 1),
+				})
+			default:
+				vrange.bounds = append(vrange.bounds, &versionBound {
+					Operator: operator,
+					Version: version,
+				})
+		}
+	}
+	return vrange, nil
+}
+
+func (vrange *VersionRange) ConstraintString() string {
+	return vrange.Specifier
+}
+
+func (vrange *VersionRange) AdmitsVersion(version spc.VersionRef) bool {
+	semver, err := ParseSemVer(version.VersionString())
+	if err != nil {
+		return false
+	}
+	if len(semver.Prerelease) > 0 && !vrange.allowPrerelease {
+		return false
+	}
+	for _, bound := range vrange.bounds {
+		if !bound.admits(semver) {
+			return false
+		}
+	}
+	return true
+}
+
+var _ spc.VersionConstraint = &VersionRange{}
+
+// ---------------------------------------- ExactVersion ----------------------------------------
+
+type ExactVersion struct {
+	Specifier string
+}
+
+func (exact *ExactVersion) ConstraintString() string {
+	return exact.Specifier
+}
+
+func (exact *ExactVersion) AdmitsVersion(version spc.VersionRef) bool {
+	if version.VersionString() == exact.Specifier {
+		return true
+	}
+	left, err := ParseSemVer(exact.Specifier)
+	if err != nil {
+		return false
+	}
+	right, err := ParseSemVer(version.VersionString())
+	if err != nil {
+		return false
+	}
+	return left.Compare(right) == spc.VRCMP_EQUAL
+}
+
+var _ spc.VersionConstraint = &ExactVersion{}
+
+func HighestAdmittedVersion(versions []spc.VersionRef, constraint spc.VersionConstraint) spc.VersionRef {
+	var best spc.VersionRef
+	for _, version := range versions {
+		if !constraint.AdmitsVersion(version) {
+			continue
+		}
+		if best == nil {
+			best = version
+			continue
+		}
+		cmp, err := version.CompareToVersion(best)
+		if err == nil && cmp == spc.VRCMP_GREATER {
+			best = version
+		}
+	}
+	return best
+}
+
+// ---------------------------------------- ConstraintSet ----------------------------------------
+
+type ConstraintSet []spc.VersionConstraint
+
+func (constraints ConstraintSet) ConstraintString() string {
+	var parts []string
+	for _, constraint := range constraints {
+		parts = append(parts, constraint.ConstraintString())
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (constraints ConstraintSet) AdmitsVersion(version spc.VersionRef) bool {
+	for _, constraint := range constraints {
+		if !constraint.AdmitsVersion(version) {
+			return false
+		}
+	}
+	return true
+}
+
+var _ spc.VersionConstraint = ConstraintSet{}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"os"
+	"io"
+	"fmt"
+	"sort"
+	"strings"
+	"os/exec"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	herr "hike/error"
+	spc "hike/spec"
+	abs "hike/abstract"
+	hlm "hike/hilvlimpl"
+)
+
+func digestEntry(hash io.Writer, fullPath string, rel string, info os.FileInfo) error {
+	switch {
+		case info.Mode() & os.ModeSymlink != 0:
+			target, err := os.Readlink(fullPath)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(hash, "l %s -> %s\n", rel, target)
+		case info.IsDir():
+			fmt.Fprintf(hash, "d %s\n", rel)
+		case info.Mode().IsRegular():
+			file, err := os.Open(fullPath)
+			if err != nil {
+				return err
+			}
+			content := sha256.New()
+			_, err = io.Copy(content, file)
+			file.Close()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(hash, "f %s %t %s\n", rel, info.Mode() & 0111 != 0, hex.EncodeToString(content.Sum(nil)))
+	}
+	return nil
+}
+
+func trackedFiles(root string) ([]string, bool) {
+	cmd := exec.Command("git", "-C", root, "ls-files", "-z", "--cached")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, false
+	}
+	var names []string
+	for _, name := range strings.Split(string(out), "\x00") {
+		if len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, true
+}
+
+// Only source content is hashed: if root lies in a git work tree, the
+// tracked files are taken as the sources; otherwise everything not
+// excluded by an ignore file is. Build outputs of the sub-project must
+// therefore be ignored in that case, or every build changes the digest.
+func DirectoryDigest(root string) (string, error) {
+	hash := sha256.New()
+	tracked, isGit := trackedFiles(root)
+	if isGit {
+		for _, rel := range tracked {
+			fullPath := filepath.Join(root, filepath.FromSlash(rel))
+			info, err := os.Lstat(fullPath)
+			switch {
+				case err == nil:
+				case os.IsNotExist(err):
+					fmt.Fprintf(hash, "x %s\n", rel)
+					continue
+				default:
+					return "", err
+			}
+			err = digestEntry(hash, fullPath, rel, info)
+			if err != nil {
+				return "", err
+			}
+		}
+		return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+	}
+	ignore := hlm.NewIgnoreMatcher(root, hlm.DefaultIgnoreFiles)
+	err := filepath.Walk(root, func(fullPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fullPath == root {
+			return nil
+		}
+		if info.Name() == ".git" || info.Name() == spc.STATE_DIR {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		skip, err := ignore.SkipWalkEntry(fullPath, info)
+		if skip || err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, fullPath)
+		if err != nil {
+			return err
+		}
+		return digestEntry(hash, fullPath, filepath.ToSlash(rel), info)
+	})
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ---------------------------------------- DirectorySnapshot ----------------------------------------
+
+type DirectorySnapshot struct {
+	Repository spc.Repository
+	Version spc.VersionRef
+	Root string
+	Project *spc.State
+	Arise *herr.AriseRef
+}
+
+func NewDirectorySnapshot(
+	repository spc.Repository,
+	version spc.VersionRef,
+	root string,
+	arise *herr.AriseRef,
+) *DirectorySnapshot {
+	return &DirectorySnapshot {
+		Repository: repository,
+		Version: version,
+		Root: root,
+		Arise: arise,
+	}
+}
+
+func (snapshot *DirectorySnapshot) OriginRepo() spc.Repository {
+	return snapshot.Repository
+}
+
+func (snapshot *DirectorySnapshot) SnapshotVersion() spc.VersionRef {
+	return snapshot.Version
+}
+
+func (snapshot *DirectorySnapshot) SnapshotRoot() string {
+	return snapshot.Root
+}
+
+func (snapshot *DirectorySnapshot) DependOnArtifact(artifactKey string) (abs.Artifact, herr.BuildError) {
+	if snapshot.Project == nil {
+		return nil, nil
+	}
+	return snapshot.Project.Artifact(&abs.ArtifactKey {
+		Project: snapshot.Project.Config.EffectiveProjectName(),
+		Artifact: artifactKey,
+	}), nil
+}
+
+func (snapshot *DirectorySnapshot) AttachProject(project *spc.State) {
+	snapshot.Project = project
+}
+
+func (snapshot *DirectorySnapshot) SnapshotDigest() (string, herr.BuildError) {
+	digest, err := DirectoryDigest(snapshot.Root)
+	if err != nil {
+		return "", &RepositoryIOError {
+			Path: snapshot.Root,
+			OSError: err,
+			RepositoryArise: snapshot.Arise,
+		}
+	}
+	return digest, nil
+}
+
+func (snapshot *DirectorySnapshot) SnapshotLockName() string {
+	return snapshot.Version.VersionString()
+}
+
+var _ spc.Snapshot = &DirectorySnapshot{}
@@ -2,17 +2,79 @@ package spec
 
 import (
 	"fmt"
-	"regexp"
+	"strings"
 	"path/filepath"
 	herr "hike/error"
 	abs "hike/abstract"
 	loc "hike/location"
 )
 
-var stringInterpolRegex *regexp.Regexp
+type StringFunction struct {
+	Arity int
+	Apply func(state *State, args []string) string
+}
+
+var stringFunctions map[string]*StringFunction
 
 func init() {
-	stringInterpolRegex = regexp.MustCompile(`\$\{[^{}]+\}`)
+	stringFunctions = map[string]*StringFunction {
+		"basename": &StringFunction {
+			Arity: 1,
+			Apply: func(state *State, args []string) string {
+				return filepath.ToSlash(filepath.Base(filepath.FromSlash(args[0])))
+			},
+		},
+		"dir": &StringFunction {
+			Arity: 1,
+			Apply: func(state *State, args []string) string {
+				return filepath.ToSlash(filepath.Dir(filepath.FromSlash(args[0])))
+			},
+		},
+		"ext": &StringFunction {
+			Arity: 1,
+			Apply: func(state *State, args []string) string {
+				return filepath.Ext(filepath.FromSlash(args[0]))
+			},
+		},
+		"noext": &StringFunction {
+			Arity: 1,
+			Apply: func(state *State, args []string) string {
+				return args[0][:len(args[0]) - len(filepath.Ext(filepath.FromSlash(args[0])))]
+			},
+		},
+		"relpath": &StringFunction {
+			Arity: 1,
+			Apply: func(state *State, args []string) string {
+				rel, err := filepath.Rel(state.Config.TopDir, state.Config.RealPath(args[0]))
+				if err != nil {
+					return args[0]
+				}
+				return filepath.ToSlash(rel)
+			},
+		},
+		"upper": &StringFunction {
+			Arity: 1,
+			Apply: func(state *State, args []string) string {
+				return strings.ToUpper(args[0])
+			},
+		},
+		"lower": &StringFunction {
+			Arity: 1,
+			Apply: func(state *State, args []string) string {
+				return strings.ToLower(args[0])
+			},
+		},
+		"replace": &StringFunction {
+			Arity: 3,
+			Apply: func(state *State, args []string) string {
+				return strings.ReplaceAll(args[2], args[0], args[1])
+			},
+		},
+	}
+}
+
+func RegisterStringFunction(name string, function *StringFunction) {
+	stringFunctions[name] = function
 }
 
 // ---------------------------------------- BuildError ----------------------------------------
@@ -135,6 +197,159 @@ func (no *NoSuchArtifactError) BuildErrorLocation() *loc.Location {
 
 var _ herr.BuildError = &NoSuchArtifactError{}
 
+type UnknownStringFunctionError struct {
+	herr.BuildErrorBase
+	Function string
+	Expression string
+	InterpolationArise *herr.AriseRef
+}
+
+func (unknown *UnknownStringFunctionError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Println("No such string function:", unknown.Function)
+	prn.Indent(1)
+	prn.Println("in expression", unknown.Expression)
+	prn.Indent(1)
+	prn.Print("during interpolation ")
+	prn.Arise(unknown.InterpolationArise, 1)
+	unknown.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (unknown *UnknownStringFunctionError) BuildErrorLocation() *loc.Location {
+	return unknown.InterpolationArise.Location
+}
+
+var _ herr.BuildError = &UnknownStringFunctionError{}
+
+type StringFunctionArityError struct {
+	herr.BuildErrorBase
+	Function string
+	Expression string
+	ExpectedCount int
+	ActualCount int
+	InterpolationArise *herr.AriseRef
+}
+
+func (arity *StringFunctionArityError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Printf(
+		"Wrong number of arguments to string function '%s': expected %d, got %d\n",
+		arity.Function,
+		arity.ExpectedCount,
+		arity.ActualCount,
+	)
+	prn.Indent(1)
+	prn.Println("in expression", arity.Expression)
+	prn.Indent(1)
+	prn.Print("during interpolation ")
+	prn.Arise(arity.InterpolationArise, 1)
+	arity.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (arity *StringFunctionArityError) BuildErrorLocation() *loc.Location {
+	return arity.InterpolationArise.Location
+}
+
+var _ herr.BuildError = &StringFunctionArityError{}
+
+type DuplicateDependencyError struct {
+	herr.BuildErrorBase
+	DependKey string
+	OldArise *herr.AriseRef
+	NewArise *herr.AriseRef
+}
+
+func (duplicate *DuplicateDependencyError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Println("Dependency key clash:", duplicate.DependKey)
+	prn.Indent(1)
+	prn.Print("between old dependency ")
+	prn.Arise(duplicate.OldArise, 1)
+	prn.Println()
+	prn.Indent(1)
+	prn.Print("and new dependency ")
+	prn.Arise(duplicate.NewArise, 1)
+	duplicate.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (duplicate *DuplicateDependencyError) BuildErrorLocation() *loc.Location {
+	return duplicate.NewArise.Location
+}
+
+var _ herr.BuildError = &DuplicateDependencyError{}
+
+type VersionConflictError struct {
+	herr.BuildErrorBase
+	DependKey string
+	Constraints []*DependState
+	Available []string
+}
+
+func (conflict *VersionConflictError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Println("Version conflict for dependency:", conflict.DependKey)
+	prn.Indent(1)
+	prn.Print("no version satisfies all of")
+	for _, ds := range conflict.Constraints {
+		prn.Println()
+		prn.Indent(2)
+		if ds.Constraint != nil {
+			prn.Printf("'%s' required by %s ", ds.Constraint.ConstraintString(), ds.ProjectLevel)
+		} else {
+			prn.Printf("any version required by %s ", ds.ProjectLevel)
+		}
+		prn.Arise(ds.Arise, 2)
+	}
+	if len(conflict.Available) > 0 {
+		prn.Println()
+		prn.Indent(1)
+		prn.Print("available versions: ", strings.Join(conflict.Available, ", "))
+	}
+	conflict.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (conflict *VersionConflictError) BuildErrorLocation() *loc.Location {
+	return conflict.Constraints[len(conflict.Constraints) - 1].Arise.Location
+}
+
+var _ herr.BuildError = &VersionConflictError{}
+
+type RepositoryConflictError struct {
+	herr.BuildErrorBase
+	DependKey string
+	First *DependState
+	Second *DependState
+}
+
+func (conflict *RepositoryConflictError) PrintBuildError(level uint) error {
+	prn := herr.NewErrorPrinter()
+	prn.Level(level)
+	prn.Println("Repository conflict for dependency:", conflict.DependKey)
+	prn.Indent(1)
+	prn.Printf("%s used by %s ", conflict.First.Repository.RepoDescription(), conflict.First.ProjectLevel)
+	prn.Arise(conflict.First.Arise, 1)
+	prn.Println()
+	prn.Indent(1)
+	prn.Printf("%s used by %s ", conflict.Second.Repository.RepoDescription(), conflict.Second.ProjectLevel)
+	prn.Arise(conflict.Second.Arise, 1)
+	conflict.InjectBacktrace(prn, 0)
+	return prn.Done()
+}
+
+func (conflict *RepositoryConflictError) BuildErrorLocation() *loc.Location {
+	return conflict.Second.Arise.Location
+}
+
+var _ herr.BuildError = &RepositoryConflictError{}
+
 // ---------------------------------------- State ----------------------------------------
 
 type PendingResolver func() herr.BuildError
@@ -165,6 +380,7 @@ func NewState(config *Config, parent *State, dependKey string) *State {
 		Parent: parent,
 		ResolveState: &ResolveState {
 			dependencies: make(map[string]*DependState),
+			selected: make(map[string]*DependState),
 		},
 		DependKey: dependKey,
 	}
@@ -211,6 +427,22 @@ func (state *State) Artifact(key *abs.ArtifactKey) abs.Artifact {
 	return state.artifacts[key.Unified()]
 }
 
+func (state *State) ResolveArtifact(key *abs.ArtifactKey) (abs.Artifact, herr.BuildError) {
+	artifact := state.artifacts[key.Unified()]
+	if artifact != nil {
+		return artifact, nil
+	}
+	ds := state.ResolveState.dependencies[key.Project]
+	if ds == nil {
+		return nil, nil
+	}
+	snapshot := ds.EffectiveSnapshot()
+	if snapshot == nil {
+		return nil, nil
+	}
+	return snapshot.DependOnArtifact(key.Artifact)
+}
+
 func (state *State) RegisterArtifact(artifact abs.Artifact, arise *herr.AriseRef) *DuplicateArtifactError {
 	ks := artifact.ArtifactKey().Unified()
 	old, present := state.artifacts[ks]
@@ -299,20 +531,147 @@ func (state *State) IntVar(key string) (int, bool) {
 	return value, exists
 }
 
-func (state *State) InterpolateString(src string) string {
-	res := stringInterpolRegex.ReplaceAllStringFunc(src, func(key string) string {
-		ikey := key[2:len(key) - 1]
-		sval, exists := state.stringVars[ikey]
-		if exists {
-			return sval
+func (state *State) VarValue(key string) (string, bool) {
+	sval, exists := state.stringVars[key]
+	if exists {
+		return sval, true
+	}
+	ival, exists := state.intVars[key]
+	if exists {
+		return fmt.Sprint(ival), true
+	}
+	return "", false
+}
+
+func (state *State) Variables() map[string]interface{} {
+	vars := make(map[string]interface{})
+	for key, value := range state.intVars {
+		vars[key] = value
+	}
+	for key, value := range state.stringVars {
+		vars[key] = value
+	}
+	return vars
+}
+
+func FindInterpolationEnd(src string, start int) int {
+	depth := 1
+	for index := start; index < len(src); index++ {
+		switch src[index] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					return index
+				}
+		}
+	}
+	return -1
+}
+
+func splitInterpolationArgs(src string) []string {
+	var args []string
+	var arg strings.Builder
+	depth := 0
+	for index := 0; index < len(src); index++ {
+		switch src[index] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			case '\\':
+				if depth == 0 && index + 1 < len(src) && src[index + 1] == ':' {
+					arg.WriteByte(':')
+					index++
+					continue
+				}
+			case ':':
+				if depth == 0 {
+					args = append(args, arg.String())
+					arg.Reset()
+					continue
+				}
 		}
-		ival, exists := state.intVars[ikey]
+		arg.WriteByte(src[index])
+	}
+	return append(args, arg.String())
+}
+
+func (state *State) interpolateExpression(
+	expr string,
+	whole string,
+	arise *herr.AriseRef,
+) (string, herr.BuildError) {
+	colon := strings.IndexRune(expr, ':')
+	if colon < 0 || strings.Contains(expr[:colon], "{") {
+		value, exists := state.VarValue(expr)
 		if exists {
-			return fmt.Sprint(ival)
+			return value, nil
+		}
+		return whole, nil
+	}
+	name := expr[:colon]
+	function := stringFunctions[name]
+	if function == nil {
+		return "", &UnknownStringFunctionError {
+			Function: name,
+			Expression: whole,
+			InterpolationArise: arise,
+		}
+	}
+	rawArgs := splitInterpolationArgs(expr[colon + 1:])
+	if len(rawArgs) != function.Arity {
+		return "", &StringFunctionArityError {
+			Function: name,
+			Expression: whole,
+			ExpectedCount: function.Arity,
+			ActualCount: len(rawArgs),
+			InterpolationArise: arise,
+		}
+	}
+	args := make([]string, len(rawArgs))
+	for index, rawArg := range rawArgs {
+		arg, err := state.InterpolateString(rawArg, arise)
+		if err != nil {
+			return "", err
+		}
+		args[index] = arg
+	}
+	return function.Apply(state, args), nil
+}
+
+// Expands ${var} and ${function:arg:...}. Arguments are split at every
+// top-level ':'; write '\:' for a literal colon within an argument. An
+// expression written as $${...} is emitted literally (minus one '$'), so
+// that e.g. shell parameter expansions can be passed through.
+func (state *State) InterpolateString(src string, arise *herr.AriseRef) (string, herr.BuildError) {
+	var sink strings.Builder
+	for {
+		start := strings.Index(src, "${")
+		if start < 0 {
+			break
+		}
+		end := FindInterpolationEnd(src, start + 2)
+		if end < 0 {
+			break
+		}
+		if start > 0 && src[start - 1] == '$' {
+			sink.WriteString(src[:start])
+			sink.WriteString(src[start + 1:end + 1])
+			src = src[end + 1:]
+			continue
+		}
+		sink.WriteString(src[:start])
+		value, err := state.interpolateExpression(src[start + 2:end], src[start:end + 1], arise)
+		if err != nil {
+			return "", err
 		}
-		return key
-	})
-	return res
+		sink.WriteString(value)
+		src = src[end + 1:]
+	}
+	sink.WriteString(src)
+	return sink.String(), nil
 }
 
 func (state *State) DependStateFor(dependKey string, create bool) *DependState {
@@ -327,7 +686,7 @@ func (state *State) DependStateFor(dependKey string, create bool) *DependState {
 	if state.Parent == nil {
 		level = "toplevel project"
 	} else {
-		level = fmt.Sprintf("project '%s'", dependKey)
+		level = fmt.Sprintf("project '%s'", state.DependKey)
 	}
 	ds = &DependState {
 		ProjectLevel: level,
@@ -336,6 +695,43 @@ func (state *State) DependStateFor(dependKey string, create bool) *DependState {
 	return ds
 }
 
+func (state *State) RootState() *State {
+	for state.Parent != nil {
+		state = state.Parent
+	}
+	return state
+}
+
+func (state *State) SelectedDependency(dependKey string) *DependState {
+	return state.RootState().ResolveState.selected[dependKey]
+}
+
+func (state *State) SelectDependency(dependKey string, ds *DependState) {
+	state.RootState().ResolveState.selected[dependKey] = ds
+}
+
+func (state *State) SetVersionLock(lock VersionLock) {
+	state.RootState().ResolveState.lock = lock
+}
+
+func (state *State) VersionLock() VersionLock {
+	return state.RootState().ResolveState.lock
+}
+
+func (state *State) DeclaredDependencies() map[string]*DependState {
+	declared := make(map[string]*DependState)
+	for dependKey, ds := range state.ResolveState.dependencies {
+		if ds.Repository != nil {
+			declared[dependKey] = ds
+		}
+	}
+	return declared
+}
+
+func (state *State) UnselectDependency(dependKey string) {
+	delete(state.RootState().ResolveState.selected, dependKey)
+}
+
 func (state *State) PushDependenciesUp() {
 	if state.Parent == nil {
 		return
@@ -350,6 +746,8 @@ func (state *State) PushDependenciesUp() {
 
 // ---------------------------------------- Config ----------------------------------------
 
+const STATE_DIR = ".hike"
+
 type Config struct {
 	ProjectName string
 	TopDir string
@@ -373,15 +771,27 @@ func (config *Config) RealPath(path string) string {
 	}
 }
 
+func (config *Config) StatePath(parts ...string) string {
+	return filepath.Join(append([]string {config.TopDir, STATE_DIR}, parts...)...)
+}
+
 // ---------------------------------------- ResolveState ----------------------------------------
 
 type ResolveState struct {
 	dependencies map[string]*DependState
+	selected map[string]*DependState
+	lock VersionLock
 }
 
 type DependState struct {
 	ProjectLevel string
 	Repository Repository
+	Specifier string
+	Version VersionRef
+	Constraint VersionConstraint
+	Snapshot Snapshot
+	Project *State
+	Arise *herr.AriseRef
 	Children []*DependState
 }
 
@@ -389,6 +799,19 @@ func (state *DependState) AddChild(child *DependState) {
 	state.Children = append(state.Children, child)
 }
 
+func (state *DependState) EffectiveSnapshot() Snapshot {
+	if state.Snapshot != nil {
+		return state.Snapshot
+	}
+	for _, child := range state.Children {
+		snapshot := child.EffectiveSnapshot()
+		if snapshot != nil {
+			return snapshot
+		}
+	}
+	return nil
+}
+
 // ---------------------------------------- VersionRef ----------------------------------------
 
 const (
@@ -413,10 +836,16 @@ type VersionRef interface {
 	CompareToVersion(other VersionRef) (int, error)
 }
 
+type VersionConstraint interface {
+	ConstraintString() string
+	AdmitsVersion(version VersionRef) bool
+}
+
 // ---------------------------------------- Repository ----------------------------------------
 
 type Repository interface {
 	RepoDescription() string
+	AvailableVersions() ([]VersionRef, herr.BuildError)
 	InternVersion(specifier string, arise *herr.AriseRef) (VersionRef, herr.BuildError)
 	AcquireSnapshot(version VersionRef) (Snapshot, herr.BuildError)
 }
@@ -426,4 +855,13 @@ type Snapshot interface {
 	SnapshotVersion() VersionRef
 	SnapshotRoot() string
 	DependOnArtifact(artifactKey string) (abs.Artifact, herr.BuildError)
+	AttachProject(project *State)
+	SnapshotDigest() (string, herr.BuildError)
+	SnapshotLockName() string
+}
+
+type VersionLock interface {
+	LockedVersion(dependKey string, repository Repository) (string, bool)
+	VerifySnapshot(dependKey string, snapshot Snapshot, arise *herr.AriseRef) herr.BuildError
+	RecordSnapshot(dependKey string, snapshot Snapshot, arise *herr.AriseRef) herr.BuildError
 }
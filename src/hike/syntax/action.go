@@ -165,6 +165,7 @@ func ParseCommandAction(parser *prs.Parser) abs.Action {
 		return nil
 	}
 	loud := false
+	var streams gen.CommandStreams
 	for csx.IsExecOption(parser) {
 		switch parser.Token.Text {
 			case "loud":
@@ -174,7 +175,10 @@ func ParseCommandAction(parser *prs.Parser) abs.Action {
 				// ignored
 				parser.Next()
 			default:
-				panic("Unrecognized exec option: " + parser.Token.Text)
+				if !csx.ParseExecStreamOption(parser, &streams, "command action", false) {
+					parser.Frame("command action", start)
+					return nil
+				}
 		}
 	}
 	arise := &herr.AriseRef {
@@ -203,6 +207,7 @@ func ParseCommandAction(parser *prs.Parser) abs.Action {
 		Loud: loud,
 	}
 	exec.Arise = arise
+	exec.CommandStreams = streams
 	for parser.IsArtifactRef(true) {
 		source := parser.ArtifactRef(&herr.AriseRef {
 			Text: "command transform source",
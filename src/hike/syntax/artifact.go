@@ -1,7 +1,11 @@
 package syntax
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
 	herr "hike/error"
+	spc "hike/spec"
 	tok "hike/token"
 	prs "hike/parser"
 	hlv "hike/hilevel"
@@ -63,6 +67,30 @@ func ParseFileArtifact(parser *prs.Parser) *con.FileArtifact {
 			} else {
 				name = con.GuessFileArtifactName(path, config.TopDir)
 			}
+			var checksum string
+			haveChecksum := false
+			if parser.IsKeyword("sha256") {
+				location := &parser.Token.Location
+				parser.Next()
+				if !parser.ExpectExp(tok.T_STRING, "expected SHA-256 digest") {
+					parser.Frame("file artifact 'sha256' option", location)
+					parser.Frame("file artifact", start)
+					return nil
+				}
+				checksum = parser.InterpolateString()
+				if !con.IsValidChecksum("sha256", checksum) {
+					parser.Fail(&con.IllegalChecksumError {
+						Algorithm: "sha256",
+						Checksum: checksum,
+						Location: &parser.Token.Location,
+					})
+					parser.Frame("file artifact 'sha256' option", location)
+					parser.Frame("file artifact", start)
+					return nil
+				}
+				parser.Next()
+				haveChecksum = true
+			}
 			var transform abs.Transform
 			haveTransform := false
 			if parser.IsTransform() {
@@ -74,24 +102,22 @@ func ParseFileArtifact(parser *prs.Parser) *con.FileArtifact {
 				haveTransform = true
 			}
 			if parser.Token.Type != tok.T_RBRACE {
-				if haveName {
-					if haveTransform {
+				switch {
+					case haveTransform:
 						parser.Die("'}'")
-					} else {
+					case haveChecksum:
 						parser.Die("transform or '}'")
-					}
-				} else {
-					if haveTransform {
-						parser.Die("'name' or '}'")
-					} else {
-						parser.Die("'name', transform or '}'")
-					}
+					case haveName:
+						parser.Die("'sha256', transform or '}'")
+					default:
+						parser.Die("'name', 'sha256', transform or '}'")
 				}
 				parser.Frame("file artifact", start)
 				return nil
 			}
 			parser.Next()
 			file := con.NewFile(*key, name, arise, path, transform)
+			file.SHA256 = checksum
 			dup := parser.SpecState().RegisterArtifact(file, arise)
 			if dup != nil {
 				parser.Fail(dup)
@@ -318,6 +344,7 @@ func ParseTreeArtifact(parser *prs.Parser) *hlm.TreeArtifact {
 				arise,
 				root,
 				nil,
+				nil,
 				false,
 			)
 			dup := specState.RegisterArtifact(tree, arise)
@@ -337,7 +364,8 @@ func ParseTreeArtifact(parser *prs.Parser) *hlm.TreeArtifact {
 			parser.Next()
 			name := ""
 			haveName := false
-			var noCache bool
+			var noCache, followSymlinks bool
+			var ignoreFiles []string
 		  opts:
 			for parser.Token.Type == tok.T_NAME {
 				switch parser.Token.Text {
@@ -355,6 +383,15 @@ func ParseTreeArtifact(parser *prs.Parser) *hlm.TreeArtifact {
 					case "noCache":
 						noCache = true
 						parser.Next()
+					case "ignoreFiles":
+						parser.Next()
+						ignoreFiles = parseIgnoreFileNames(parser)
+					case "followSymlinks":
+						followSymlinks = true
+						parser.Next()
+					case "preserveSymlinks":
+						followSymlinks = false
+						parser.Next()
 					default:
 						break opts
 				}
@@ -371,17 +408,30 @@ func ParseTreeArtifact(parser *prs.Parser) *hlm.TreeArtifact {
 				}
 				filters = append(filters, filter)
 			}
+			var transform abs.Transform
+			if parser.IsTransform() {
+				transform = parser.Transform()
+				if transform == nil {
+					parser.Frame("tree artifact", start)
+					return nil
+				}
+			}
 			if parser.Token.Type != tok.T_RBRACE {
-				if len(filters) > 0 {
-					parser.Die("file filter or '}'")
-				} else {
-					parser.Die("tree artifact option, file filter or '}'")
+				switch {
+					case transform != nil:
+						parser.Die("'}'")
+					case len(filters) > 0:
+						parser.Die("file filter, transform or '}'")
+					default:
+						parser.Die("tree artifact option, file filter, transform or '}'")
 				}
 				parser.Frame("tree artifact", start)
 				return nil
 			}
 			parser.Next()
-			tree := hlm.NewTreeArtifact(*key, name, arise, root, filters, noCache)
+			tree := hlm.NewTreeArtifact(*key, name, arise, root, filters, ignoreFiles, noCache)
+			tree.FollowSymlinks = followSymlinks
+			tree.GeneratingTransform = transform
 			dup := specState.RegisterArtifact(tree, arise)
 			if dup != nil {
 				parser.Fail(dup)
@@ -480,3 +530,254 @@ func TopSplitArtifact(parser *prs.Parser) abs.Artifact {
 		return nil
 	}
 }
+
+func ArchiveCacheDir(config *spc.Config, key *abs.ArtifactKey) string {
+	digest := sha256.Sum256([]byte(key.Unified()))
+	return config.StatePath("entries", hex.EncodeToString(digest[:8]))
+}
+
+func StampPath(config *spc.Config, key *abs.ArtifactKey) string {
+	digest := sha256.Sum256([]byte(key.Unified()))
+	return config.StatePath("stamps", hex.EncodeToString(digest[:8]))
+}
+
+func ParseArchiveEntryArtifact(parser *prs.Parser) *hlm.ArchiveEntryArtifact {
+	if !parser.ExpectKeyword("archiveEntry") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	if !parser.ExpectExp(tok.T_STRING, "artifact key") {
+		parser.Frame("archive entry artifact", start)
+		return nil
+	}
+	specState := parser.SpecState()
+	key := prs.SplitArtifactKey(parser.InterpolateString(), specState.Config)
+	parser.Next()
+	arise := &herr.AriseRef {
+		Text: "'archiveEntry' stanza",
+		Location: start,
+	}
+	if !parser.Expect(tok.T_LBRACE) {
+		parser.Frame("archive entry artifact", start)
+		return nil
+	}
+	parser.Next()
+	archiveRef := parser.ArtifactRef(arise, false)
+	if archiveRef == nil {
+		parser.Frame("archive entry artifact", start)
+		return nil
+	}
+	if !parser.ExpectExp(tok.T_STRING, "entry path") {
+		parser.Frame("archive entry artifact", start)
+		return nil
+	}
+	entry, _, _ := hlm.SplitArchiveEntryName(parser.InterpolateString())
+	parser.Next()
+	var name string
+	if parser.IsKeyword("name") {
+		nameStart := &parser.Token.Location
+		parser.Next()
+		if !parser.ExpectExp(tok.T_STRING, "artifact name") {
+			parser.Frame("archive entry artifact 'name' option", nameStart)
+			parser.Frame("archive entry artifact", start)
+			return nil
+		}
+		name = parser.InterpolateString()
+		parser.Next()
+	}
+	if !parser.Expect(tok.T_RBRACE) {
+		parser.Frame("archive entry artifact", start)
+		return nil
+	}
+	parser.Next()
+	cachePath := filepath.Join(ArchiveCacheDir(specState.Config, key), filepath.FromSlash(entry))
+	artifact := hlm.NewArchiveEntryArtifact(*key, name, arise, nil, entry, cachePath)
+	archiveRef.InjectArtifact(specState, func(archive abs.Artifact) {
+		artifact.Archive = archive
+	})
+	dup := specState.RegisterArtifact(artifact, arise)
+	if dup != nil {
+		parser.Fail(dup)
+		parser.Frame("archive entry artifact", start)
+		return nil
+	}
+	return artifact
+}
+
+func TopArchiveEntryArtifact(parser *prs.Parser) abs.Artifact {
+	artifact := ParseArchiveEntryArtifact(parser)
+	if artifact != nil {
+		return artifact
+	} else {
+		return nil
+	}
+}
+
+func ParseSymlinkArtifact(parser *prs.Parser) *hlm.SymlinkArtifact {
+	if !parser.ExpectKeyword("symlinkTo") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	if !parser.ExpectExp(tok.T_STRING, "artifact key") {
+		parser.Frame("symlink artifact", start)
+		return nil
+	}
+	specState := parser.SpecState()
+	key := prs.SplitArtifactKey(parser.InterpolateString(), specState.Config)
+	parser.Next()
+	arise := &herr.AriseRef {
+		Text: "'symlinkTo' stanza",
+		Location: start,
+	}
+	if !parser.Expect(tok.T_LBRACE) {
+		parser.Frame("symlink artifact", start)
+		return nil
+	}
+	parser.Next()
+	if !parser.ExpectExp(tok.T_STRING, "link path") {
+		parser.Frame("symlink artifact", start)
+		return nil
+	}
+	path := specState.Config.RealPath(parser.InterpolateString())
+	parser.Next()
+	name := con.GuessFileArtifactName(path, specState.Config.TopDir)
+	if parser.IsKeyword("name") {
+		nameStart := &parser.Token.Location
+		parser.Next()
+		if !parser.ExpectExp(tok.T_STRING, "artifact name") {
+			parser.Frame("symlink artifact 'name' option", nameStart)
+			parser.Frame("symlink artifact", start)
+			return nil
+		}
+		name = parser.InterpolateString()
+		parser.Next()
+	}
+	artifact := hlm.NewSymlinkArtifact(*key, name, arise, path, "", nil, false, specState.Config.TopDir)
+	switch {
+		case parser.IsKeyword("target"):
+			targetStart := &parser.Token.Location
+			parser.Next()
+			if !parser.ExpectExp(tok.T_STRING, "link target") {
+				parser.Frame("symlink artifact 'target' option", targetStart)
+				parser.Frame("symlink artifact", start)
+				return nil
+			}
+			artifact.Target = parser.InterpolateString()
+			parser.Next()
+		case parser.IsKeyword("to"):
+			parser.Next()
+			targetRef := parser.ArtifactRef(arise, false)
+			if targetRef == nil {
+				parser.Frame("symlink artifact", start)
+				return nil
+			}
+			targetRef.InjectArtifact(specState, func(target abs.Artifact) {
+				artifact.TargetArtifact = target
+			})
+			if parser.IsKeyword("relative") {
+				artifact.Relative = true
+				parser.Next()
+			}
+		default:
+			parser.Die("'target' or 'to'")
+			parser.Frame("symlink artifact", start)
+			return nil
+	}
+	if !parser.Expect(tok.T_RBRACE) {
+		parser.Frame("symlink artifact", start)
+		return nil
+	}
+	parser.Next()
+	dup := specState.RegisterArtifact(artifact, arise)
+	if dup != nil {
+		parser.Fail(dup)
+		parser.Frame("symlink artifact", start)
+		return nil
+	}
+	return artifact
+}
+
+func TopSymlinkArtifact(parser *prs.Parser) abs.Artifact {
+	artifact := ParseSymlinkArtifact(parser)
+	if artifact != nil {
+		return artifact
+	} else {
+		return nil
+	}
+}
+
+func ParseStampArtifact(parser *prs.Parser) *hlm.StampArtifact {
+	if !parser.ExpectKeyword("stamp") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	if !parser.ExpectExp(tok.T_STRING, "artifact key") {
+		parser.Frame("stamp artifact", start)
+		return nil
+	}
+	specState := parser.SpecState()
+	key := prs.SplitArtifactKey(parser.InterpolateString(), specState.Config)
+	parser.Next()
+	arise := &herr.AriseRef {
+		Text: "'stamp' stanza",
+		Location: start,
+	}
+	if !parser.Expect(tok.T_LBRACE) {
+		parser.Frame("stamp artifact", start)
+		return nil
+	}
+	parser.Next()
+	var name string
+	haveName := false
+	if parser.IsKeyword("name") {
+		nameStart := &parser.Token.Location
+		parser.Next()
+		if !parser.ExpectExp(tok.T_STRING, "artifact name") {
+			parser.Frame("stamp artifact 'name' option", nameStart)
+			parser.Frame("stamp artifact", start)
+			return nil
+		}
+		name = parser.InterpolateString()
+		parser.Next()
+		haveName = true
+	}
+	if !parser.IsTransform() {
+		if haveName {
+			parser.Die("transform")
+		} else {
+			parser.Die("'name' or transform")
+		}
+		parser.Frame("stamp artifact", start)
+		return nil
+	}
+	transform := parser.Transform()
+	if transform == nil {
+		parser.Frame("stamp artifact", start)
+		return nil
+	}
+	if !parser.Expect(tok.T_RBRACE) {
+		parser.Frame("stamp artifact", start)
+		return nil
+	}
+	parser.Next()
+	artifact := hlm.NewStampArtifact(*key, name, arise, StampPath(specState.Config, key), transform)
+	dup := specState.RegisterArtifact(artifact, arise)
+	if dup != nil {
+		parser.Fail(dup)
+		parser.Frame("stamp artifact", start)
+		return nil
+	}
+	return artifact
+}
+
+func TopStampArtifact(parser *prs.Parser) abs.Artifact {
+	artifact := ParseStampArtifact(parser)
+	if artifact != nil {
+		return artifact
+	} else {
+		return nil
+	}
+}
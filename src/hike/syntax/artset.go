@@ -55,7 +55,7 @@ func ParseArtifactScanDir(parser *prs.Parser) []abs.Artifact {
 		case tok.T_STRING:
 			root := specState.Config.RealPath(parser.InterpolateString())
 			parser.Next()
-			return doArtifactScanDir(parser, start, root, "", "", "", nil)
+			return doArtifactScanDir(parser, start, root, "", "", "", nil, nil)
 		case tok.T_LBRACE:
 			parser.Next()
 			if !parser.ExpectExp(tok.T_STRING, "scan root directory") {
@@ -67,10 +67,15 @@ func ParseArtifactScanDir(parser *prs.Parser) []abs.Artifact {
 			var key, name, base, optdesc string
 			var optval *string
 			var isPath bool
+			var ignoreFiles []string
 			base = root
 		  opts:
 			for parser.Token.Type == tok.T_NAME {
 				switch parser.Token.Text {
+					case "ignoreFiles":
+						parser.Next()
+						ignoreFiles = parseIgnoreFileNames(parser)
+						continue opts
 					case "key":
 						optval = &key
 						optdesc = "artifact key prefix"
@@ -119,7 +124,7 @@ func ParseArtifactScanDir(parser *prs.Parser) []abs.Artifact {
 				return nil
 			}
 			parser.Next()
-			return doArtifactScanDir(parser, start, root, key, name, base, filters)
+			return doArtifactScanDir(parser, start, root, key, name, base, filters, ignoreFiles)
 		default:
 			parser.Die("string (scan root directory) or '{'")
 			parser.Frame("'scandir' artifact set", start)
@@ -135,6 +140,7 @@ func doArtifactScanDir(
 	namePrefix string,
 	baseDir string,
 	filters []hlv.FileFilter,
+	ignoreFiles []string,
 ) []abs.Artifact {
 	specState := parser.SpecState()
 	config := specState.Config
@@ -143,12 +149,30 @@ func doArtifactScanDir(
 		Text: "'scandir' artifact set",
 		Location: start,
 	}
+	err := hlm.PrepareFileFilters(filters)
+	if err != nil {
+		parser.Fail(err)
+		return nil
+	}
 	var artifacts []abs.Artifact
+	var ignore *hlm.IgnoreMatcher
+	if len(ignoreFiles) > 0 {
+		ignore = hlm.NewIgnoreMatcher(root, ignoreFiles)
+	}
 	outerr := filepath.Walk(root, func(fullPath string, info os.FileInfo, inerr error) error {
 		if inerr != nil {
 			return inerr
 		}
+		if ignore != nil {
+			skip, ierr := ignore.SkipWalkEntry(fullPath, info)
+			if skip {
+				return ierr
+			}
+		}
 		if !hlm.AllFileFilters(fullPath, root, info, filters) {
+			if info.IsDir() && hlm.AnyFileFilterPrunes(fullPath, root, info, filters) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 		name := con.GuessFileArtifactName(fullPath, baseDir)
@@ -163,6 +187,9 @@ func doArtifactScanDir(
 			return nil
 		}
 		artifacts = append(artifacts, artifact)
+		if info.IsDir() && hlm.AnyFileFilterPrunes(fullPath, root, info, filters) {
+			return filepath.SkipDir
+		}
 		return nil
 	})
 	if outerr != nil {
@@ -175,3 +202,203 @@ func doArtifactScanDir(
 	}
 	return artifacts
 }
+
+func parseIgnoreFileNames(parser *prs.Parser) []string {
+	var ignoreFiles []string
+	for parser.Token.Type == tok.T_STRING {
+		ignoreFiles = append(ignoreFiles, parser.InterpolateString())
+		parser.Next()
+	}
+	if len(ignoreFiles) == 0 {
+		ignoreFiles = hlm.DefaultIgnoreFiles
+	}
+	return ignoreFiles
+}
+
+func ParseArtifactArchiveScan(parser *prs.Parser) []abs.Artifact {
+	if !parser.ExpectKeyword("archiveScan") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	arise := &herr.AriseRef {
+		Text: "'archiveScan' artifact set",
+		Location: start,
+	}
+	specState := parser.SpecState()
+	if parser.Token.Type != tok.T_LBRACE {
+		archiveName := archiveRefName(parser)
+		archiveRef := parser.ArtifactRef(arise, false)
+		if archiveRef == nil {
+			parser.Frame("'archiveScan' artifact set", start)
+			return nil
+		}
+		return doArtifactArchiveScan(parser, start, archiveRef, archiveName, "", "", "", nil)
+	}
+	parser.Next()
+	archiveName := archiveRefName(parser)
+	archiveRef := parser.ArtifactRef(arise, false)
+	if archiveRef == nil {
+		parser.Frame("'archiveScan' artifact set", start)
+		return nil
+	}
+	var key, name, cacheDir, optdesc string
+	var optval *string
+	var isPath bool
+  opts:
+	for parser.Token.Type == tok.T_NAME {
+		switch parser.Token.Text {
+			case "key":
+				optval = &key
+				optdesc = "artifact key prefix"
+				isPath = false
+			case "name":
+				optval = &name
+				optdesc = "artifact name prefix"
+				isPath = false
+			case "cacheDir":
+				optval = &cacheDir
+				optdesc = "extraction cache directory"
+				isPath = true
+			default:
+				break opts
+		}
+		optkey := &parser.Token.Location
+		parser.Next()
+		if !parser.ExpectExp(tok.T_STRING, optdesc) {
+			parser.Frame(fmt.Sprintf("'archiveScan' artifact set option '%s'", optdesc), optkey)
+			parser.Frame("'archiveScan' artifact set", start)
+			return nil
+		}
+		if isPath {
+			*optval = specState.Config.RealPath(parser.InterpolateString())
+		} else {
+			*optval = parser.InterpolateString()
+		}
+		parser.Next()
+	}
+	var filters []hlv.FileFilter
+	for parser.IsFileFilter() {
+		filter := parser.FileFilter()
+		if filter == nil {
+			parser.Frame("'archiveScan' artifact set", start)
+			return nil
+		}
+		filters = append(filters, filter)
+	}
+	if parser.Token.Type != tok.T_RBRACE {
+		if len(filters) > 0 {
+			parser.Die("file filter or '}'")
+		} else {
+			parser.Die("'archiveScan' artifact set option, file filter or '}'")
+		}
+		parser.Frame("'archiveScan' artifact set", start)
+		return nil
+	}
+	parser.Next()
+	return doArtifactArchiveScan(parser, start, archiveRef, archiveName, key, name, cacheDir, filters)
+}
+
+func archiveRefName(parser *prs.Parser) string {
+	if parser.Token.Type == tok.T_STRING {
+		return prs.SplitArtifactKey(parser.InterpolateString(), parser.SpecState().Config).Unified()
+	} else {
+		return "(inline artifact)"
+	}
+}
+
+// The archive is read while parsing, so only archives that already exist
+// at that point (i.e. checked into the source tree) can be scanned; one
+// produced by the build yields an ArchiveNotAvailableError.
+func doArtifactArchiveScan(
+	parser *prs.Parser,
+	start *loc.Location,
+	archiveRef prs.ArtifactRef,
+	archiveName string,
+	keyPrefix string,
+	namePrefix string,
+	cacheDir string,
+	filters []hlv.FileFilter,
+) []abs.Artifact {
+	specState := parser.SpecState()
+	config := specState.Config
+	arise := &herr.AriseRef {
+		Text: "'archiveScan' artifact set",
+		Location: start,
+	}
+	var archive abs.Artifact
+	archiveRef.InjectArtifact(specState, func(artifact abs.Artifact) {
+		archive = artifact
+	})
+	var archPaths []string
+	if archive != nil {
+		var err herr.BuildError
+		archPaths, err = archive.PathNames(nil)
+		if err != nil {
+			parser.Fail(err)
+			parser.Frame("'archiveScan' artifact set", start)
+			return nil
+		}
+	}
+	if len(archPaths) == 1 {
+		_, oserr := os.Stat(archPaths[0])
+		if os.IsNotExist(oserr) {
+			archPaths = nil
+		}
+	}
+	if len(archPaths) != 1 {
+		parser.Fail(&hlm.ArchiveNotAvailableError {
+			Archive: archiveName,
+			ScanArise: arise,
+		})
+		parser.Frame("'archiveScan' artifact set", start)
+		return nil
+	}
+	archiveKey := archive.ArtifactKey()
+	if len(keyPrefix) == 0 {
+		keyPrefix = archiveKey.Artifact + "!"
+	}
+	if len(namePrefix) == 0 {
+		namePrefix = archive.DisplayName() + "!"
+	}
+	if len(cacheDir) == 0 {
+		cacheDir = ArchiveCacheDir(config, archiveKey)
+	}
+	err := hlm.PrepareFileFilters(filters)
+	if err != nil {
+		parser.Fail(err)
+		parser.Frame("'archiveScan' artifact set", start)
+		return nil
+	}
+	var artifacts []abs.Artifact
+	outerr := hlm.WalkArchive(archPaths[0], func(member *hlm.ArchiveMember) (bool, error) {
+		if !member.Mode.IsRegular() {
+			return true, nil
+		}
+		if !hlm.AllFileFilters(filepath.FromSlash(member.Path), "", member.Info, filters) {
+			return true, nil
+		}
+		key := abs.ArtifactKey {
+			Project: config.EffectiveProjectName(),
+			Artifact: keyPrefix + member.Path,
+		}
+		cachePath := filepath.Join(cacheDir, filepath.FromSlash(member.Path))
+		artifact := hlm.NewArchiveEntryArtifact(key, namePrefix + member.Path, arise, archive, member.Path, cachePath)
+		dup := specState.RegisterArtifact(artifact, arise)
+		if dup != nil {
+			parser.Fail(dup)
+			return false, nil
+		}
+		artifacts = append(artifacts, artifact)
+		return true, nil
+	})
+	if outerr != nil {
+		parser.Fail(&hlm.ReadArchiveError {
+			Archive: archPaths[0],
+			LibError: outerr,
+			OperationArise: arise,
+		})
+		return nil
+	}
+	return artifacts
+}
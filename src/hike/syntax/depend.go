@@ -0,0 +1,445 @@
+package syntax
+
+import (
+	"sort"
+	"strings"
+	"path/filepath"
+	herr "hike/error"
+	spc "hike/spec"
+	tok "hike/token"
+	prs "hike/parser"
+	rdr "hike/reader"
+	rpo "hike/repository"
+)
+
+const DEPEND_HIKEFILE = "hikefile"
+
+func LoadSubProject(
+	knownStructures *prs.KnownStructures,
+	parent *spc.State,
+	dependKey string,
+	snapshot spc.Snapshot,
+) (*spc.State, herr.BuildError) {
+	root := snapshot.SnapshotRoot()
+	hikefile := filepath.Join(root, DEPEND_HIKEFILE)
+	config := &spc.Config {
+		ProjectName: dependKey,
+		TopDir: root,
+		CurrentHikefile: hikefile,
+	}
+	subState := spc.NewState(config, parent, dependKey)
+	err := rdr.ReadFile(hikefile, knownStructures, subState)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.AttachProject(subState)
+	return subState, nil
+}
+
+func ParseDependSpecifier(ds *spc.DependState, specifier string, arise *herr.AriseRef) herr.BuildError {
+	ds.Specifier = specifier
+	switch {
+		case len(specifier) == 0:
+		case rpo.IsVersionRange(specifier):
+			vrange, nerr := rpo.ParseVersionRange(specifier)
+			if nerr != nil {
+				return &rpo.IllegalVersionSpecifierError {
+					Specifier: specifier,
+					Reason: nerr.Error(),
+					SpecifierArise: arise,
+				}
+			}
+			ds.Constraint = vrange
+		default:
+			ds.Constraint = &rpo.ExactVersion {
+				Specifier: specifier,
+			}
+	}
+	return nil
+}
+
+type dependDeclaration struct {
+	owner *spc.State
+	ds *spc.DependState
+}
+
+func gatherDependencies(rootState *spc.State) (map[string][]*dependDeclaration, []string) {
+	declarations := make(map[string][]*dependDeclaration)
+	var keys []string
+	visited := make(map[*spc.State]bool)
+	var walk func(*spc.State)
+	walk = func(state *spc.State) {
+		visited[state] = true
+		declared := state.DeclaredDependencies()
+		var stateKeys []string
+		for dependKey, _ := range declared {
+			stateKeys = append(stateKeys, dependKey)
+		}
+		sort.Strings(stateKeys)
+		for _, dependKey := range stateKeys {
+			if len(declarations[dependKey]) == 0 {
+				keys = append(keys, dependKey)
+			}
+			declarations[dependKey] = append(declarations[dependKey], &dependDeclaration {
+				owner: state,
+				ds: declared[dependKey],
+			})
+		}
+		for _, dependKey := range stateKeys {
+			selected := rootState.SelectedDependency(dependKey)
+			if selected != nil && !visited[selected.Project] {
+				walk(selected.Project)
+			}
+		}
+	}
+	walk(rootState)
+	return declarations, keys
+}
+
+func dependConstraints(declarations []*dependDeclaration) rpo.ConstraintSet {
+	var constraints rpo.ConstraintSet
+	for _, declaration := range declarations {
+		if declaration.ds.Constraint != nil {
+			constraints = append(constraints, declaration.ds.Constraint)
+		}
+	}
+	return constraints
+}
+
+func dependConflict(
+	dependKey string,
+	declarations []*dependDeclaration,
+	available []spc.VersionRef,
+) *spc.VersionConflictError {
+	conflict := &spc.VersionConflictError {
+		DependKey: dependKey,
+	}
+	for _, declaration := range declarations {
+		conflict.Constraints = append(conflict.Constraints, declaration.ds)
+	}
+	for _, version := range available {
+		conflict.Available = append(conflict.Available, version.VersionString())
+	}
+	return conflict
+}
+
+func selectDependVersion(
+	dependKey string,
+	declarations []*dependDeclaration,
+	lock spc.VersionLock,
+) (spc.VersionRef, herr.BuildError) {
+	first := declarations[0].ds
+	repository := first.Repository
+	constraints := dependConstraints(declarations)
+	if lock != nil {
+		lockedName, present := lock.LockedVersion(dependKey, repository)
+		if present {
+			locked, err := repository.InternVersion(lockedName, first.Arise)
+			if err == nil && constraints.AdmitsVersion(locked) {
+				return locked, nil
+			}
+		}
+	}
+	if len(constraints) == 0 {
+		return repository.InternVersion("", first.Arise)
+	}
+	for _, declaration := range declarations {
+		ds := declaration.ds
+		if len(ds.Specifier) == 0 || rpo.IsVersionRange(ds.Specifier) {
+			continue
+		}
+		version, err := repository.InternVersion(ds.Specifier, ds.Arise)
+		if err != nil {
+			return nil, err
+		}
+		if !constraints.AdmitsVersion(version) {
+			return nil, dependConflict(dependKey, declarations, nil)
+		}
+		return version, nil
+	}
+	available, err := repository.AvailableVersions()
+	if err != nil {
+		return nil, err
+	}
+	version := rpo.HighestAdmittedVersion(available, constraints)
+	if version == nil {
+		if len(declarations) == 1 {
+			var names []string
+			for _, candidate := range available {
+				names = append(names, candidate.VersionString())
+			}
+			return nil, &rpo.NoSuchVersionError {
+				Repository: repository.RepoDescription(),
+				Version: first.Specifier,
+				Available: names,
+				RepositoryArise: first.Arise,
+			}
+		}
+		return nil, dependConflict(dependKey, declarations, available)
+	}
+	return version, nil
+}
+
+func selectionSignature(rootState *spc.State, keys []string) string {
+	var parts []string
+	for _, dependKey := range keys {
+		selected := rootState.SelectedDependency(dependKey)
+		if selected != nil {
+			parts = append(parts, dependKey + "=" + selected.Version.VersionString())
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func ResolveDependencies(rootState *spc.State, knownStructures *prs.KnownStructures) herr.BuildError {
+	lock := rootState.VersionLock()
+	seen := make(map[string]bool)
+	selectedKeys := make(map[string]bool)
+	var loaded []*spc.State
+	for {
+		declarations, keys := gatherDependencies(rootState)
+		for dependKey, _ := range selectedKeys {
+			if len(declarations[dependKey]) == 0 {
+				rootState.UnselectDependency(dependKey)
+				delete(selectedKeys, dependKey)
+			}
+		}
+		seen[selectionSignature(rootState, keys)] = true
+		changed := false
+		for _, dependKey := range keys {
+			group := declarations[dependKey]
+			first := group[0].ds
+			description := first.Repository.RepoDescription()
+			for _, declaration := range group[1:] {
+				if declaration.ds.Repository.RepoDescription() != description {
+					return &spc.RepositoryConflictError {
+						DependKey: dependKey,
+						First: first,
+						Second: declaration.ds,
+					}
+				}
+			}
+			current := rootState.SelectedDependency(dependKey)
+			if current != nil && dependConstraints(group).AdmitsVersion(current.Version) {
+				continue
+			}
+			version, err := selectDependVersion(dependKey, group, lock)
+			if err != nil {
+				return err
+			}
+			snapshot, err := first.Repository.AcquireSnapshot(version)
+			if err != nil {
+				return err
+			}
+			selection := &spc.DependState {
+				ProjectLevel: first.ProjectLevel,
+				Repository: first.Repository,
+				Version: snapshot.SnapshotVersion(),
+				Snapshot: snapshot,
+				Arise: first.Arise,
+			}
+			rootState.SelectDependency(dependKey, selection)
+			if seen[selectionSignature(rootState, keys)] {
+				return dependConflict(dependKey, group, nil)
+			}
+			selection.Project, err = LoadSubProject(knownStructures, group[0].owner, dependKey, snapshot)
+			if err != nil {
+				return err
+			}
+			selectedKeys[dependKey] = true
+			loaded = append(loaded, selection.Project)
+			changed = true
+			break
+		}
+		if !changed {
+			return finishDependencies(rootState, declarations, keys, loaded)
+		}
+	}
+}
+
+func finishDependencies(
+	rootState *spc.State,
+	declarations map[string][]*dependDeclaration,
+	keys []string,
+	loaded []*spc.State,
+) herr.BuildError {
+	lock := rootState.VersionLock()
+	live := make(map[*spc.State]bool)
+	for _, dependKey := range keys {
+		selected := rootState.SelectedDependency(dependKey)
+		live[selected.Project] = true
+		selected.Project.Parent = declarations[dependKey][0].owner
+		for _, declaration := range declarations[dependKey] {
+			declaration.ds.Version = selected.Version
+			declaration.ds.Snapshot = selected.Snapshot
+		}
+		if lock == nil {
+			continue
+		}
+		var err herr.BuildError
+		lockedName, present := lock.LockedVersion(dependKey, selected.Repository)
+		if present && lockedName == selected.Snapshot.SnapshotLockName() {
+			err = lock.VerifySnapshot(dependKey, selected.Snapshot, selected.Arise)
+		} else {
+			err = lock.RecordSnapshot(dependKey, selected.Snapshot, selected.Arise)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for index := len(loaded) - 1; index >= 0; index-- {
+		if live[loaded[index]] {
+			loaded[index].PushDependenciesUp()
+		}
+	}
+	for index := len(loaded) - 1; index >= 0; index-- {
+		if live[loaded[index]] {
+			err := loaded[index].Compile()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func ParseDepend(parser *prs.Parser) *spc.DependState {
+	if !parser.ExpectKeyword("depend") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	if !parser.ExpectExp(tok.T_STRING, "dependency key") {
+		parser.Frame("'depend' directive", start)
+		return nil
+	}
+	dependKey := parser.InterpolateString()
+	parser.Next()
+	arise := &herr.AriseRef {
+		Text: "'depend' directive",
+		Location: start,
+	}
+	if !parser.Expect(tok.T_LBRACE) {
+		parser.Frame("'depend' directive", start)
+		return nil
+	}
+	parser.Next()
+	if !parser.ExpectKeyword("repository") {
+		parser.Frame("'depend' directive", start)
+		return nil
+	}
+	parser.Next()
+	repository := parser.Repository()
+	if repository == nil {
+		parser.Frame("'depend' directive", start)
+		return nil
+	}
+	specifier := ""
+	haveVersion := false
+	versionArise := arise
+	if parser.IsKeyword("version") {
+		versionLocation := &parser.Token.Location
+		parser.Next()
+		if !parser.ExpectExp(tok.T_STRING, "version specifier") {
+			parser.Frame("'version' option", versionLocation)
+			parser.Frame("'depend' directive", start)
+			return nil
+		}
+		specifier = parser.InterpolateString()
+		versionArise = &herr.AriseRef {
+			Text: "version specifier",
+			Location: &parser.Token.Location,
+		}
+		parser.Next()
+		haveVersion = true
+	}
+	if parser.Token.Type != tok.T_RBRACE {
+		if !haveVersion {
+			parser.Die("'version' or '}'")
+		} else {
+			parser.Die("'}'")
+		}
+		parser.Frame("'depend' directive", start)
+		return nil
+	}
+	parser.Next()
+	ds := parser.SpecState().DependStateFor(dependKey, true)
+	if ds.Repository != nil {
+		parser.Fail(&spc.DuplicateDependencyError {
+			DependKey: dependKey,
+			OldArise: ds.Arise,
+			NewArise: arise,
+		})
+		return nil
+	}
+	ds.Repository = repository
+	ds.Arise = arise
+	err := ParseDependSpecifier(ds, specifier, versionArise)
+	if err != nil {
+		parser.Fail(err)
+		parser.Frame("'depend' directive", start)
+		return nil
+	}
+	return ds
+}
+
+func TopDepend(parser *prs.Parser) {
+	ParseDepend(parser)
+}
+
+func ParseLocalRepository(parser *prs.Parser) *rpo.LocalRepository {
+	if !parser.ExpectKeyword("local") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	if !parser.ExpectExp(tok.T_STRING, "repository directory path") {
+		parser.Frame("local repository", start)
+		return nil
+	}
+	path := parser.SpecState().Config.RealPath(parser.InterpolateString())
+	parser.Next()
+	return rpo.NewLocalRepository(path, &herr.AriseRef {
+		Text: "'local' repository",
+		Location: start,
+	})
+}
+
+func TopLocalRepository(parser *prs.Parser) spc.Repository {
+	repository := ParseLocalRepository(parser)
+	if repository != nil {
+		return repository
+	} else {
+		return nil
+	}
+}
+
+func ParseGitRepository(parser *prs.Parser) *rpo.GitRepository {
+	if !parser.ExpectKeyword("git") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	if !parser.ExpectExp(tok.T_STRING, "repository URL or path") {
+		parser.Frame("git repository", start)
+		return nil
+	}
+	url := parser.InterpolateString()
+	if !rpo.IsRemoteGitURL(url) {
+		url = parser.SpecState().Config.RealPath(url)
+	}
+	parser.Next()
+	return rpo.NewGitRepository(url, &herr.AriseRef {
+		Text: "'git' repository",
+		Location: start,
+	})
+}
+
+func TopGitRepository(parser *prs.Parser) spc.Repository {
+	repository := ParseGitRepository(parser)
+	if repository != nil {
+		return repository
+	} else {
+		return nil
+	}
+}
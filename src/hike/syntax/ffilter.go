@@ -1,9 +1,13 @@
 package syntax
 
 import (
+	"time"
+	"regexp"
+	herr "hike/error"
 	tok "hike/token"
 	prs "hike/parser"
 	hlv "hike/hilevel"
+	abs "hike/abstract"
 	hlm "hike/hilvlimpl"
 )
 
@@ -65,6 +69,216 @@ func TopWildcardFileFilter(parser *prs.Parser) hlv.FileFilter {
 	}
 }
 
+func ParseGlobFileFilter(parser *prs.Parser) *hlm.GlobFileFilter {
+	if !parser.ExpectKeyword("glob") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	if !parser.ExpectExp(tok.T_STRING, "glob path pattern") {
+		parser.Frame("'glob' file filter", start)
+		return nil
+	}
+	pattern := parser.InterpolateString()
+	glob, gerr := hlm.CompileGlob(pattern)
+	if gerr != nil {
+		parser.Fail(&hlm.IllegalGlobError {
+			Glob: pattern,
+			LibError: gerr,
+			PatternArise: &herr.AriseRef {
+				Text: "glob path pattern",
+				Location: &parser.Token.Location,
+			},
+		})
+		parser.Frame("'glob' file filter", start)
+		return nil
+	}
+	parser.Next()
+	return hlm.NewGlobFileFilter(pattern, glob)
+}
+
+func TopGlobFileFilter(parser *prs.Parser) hlv.FileFilter {
+	filter := ParseGlobFileFilter(parser)
+	if filter != nil {
+		return filter
+	} else {
+		return nil
+	}
+}
+
+func ParseRegexFileFilter(parser *prs.Parser) *hlm.RegexFileFilter {
+	if !parser.ExpectKeyword("regex") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	if !parser.ExpectExp(tok.T_STRING, "path regex") {
+		parser.Frame("'regex' file filter", start)
+		return nil
+	}
+	regexText := parser.InterpolateString()
+	regex, rerr := regexp.Compile(regexText)
+	if rerr != nil {
+		parser.Fail(&hlm.IllegalRegexError {
+			Regex: regexText,
+			LibError: rerr,
+			PatternArise: &herr.AriseRef {
+				Text: "path regex",
+				Location: &parser.Token.Location,
+			},
+		})
+		parser.Frame("'regex' file filter", start)
+		return nil
+	}
+	parser.Next()
+	return hlm.NewRegexFileFilter(regex, regexText)
+}
+
+func TopRegexFileFilter(parser *prs.Parser) hlv.FileFilter {
+	filter := ParseRegexFileFilter(parser)
+	if filter != nil {
+		return filter
+	} else {
+		return nil
+	}
+}
+
+func ParseSizeFileFilter(parser *prs.Parser) *hlm.SizeFileFilter {
+	if !parser.ExpectKeyword("size") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	var spec string
+	switch parser.Token.Type {
+		case tok.T_STRING:
+			spec = parser.InterpolateString()
+		case tok.T_INT:
+			spec = parser.Token.Text
+		default:
+			parser.Die("string (size comparison) or int")
+			parser.Frame("'size' file filter", start)
+			return nil
+	}
+	comparison, size, serr := hlm.ParseSizeSpec(spec)
+	if serr != nil {
+		parser.Fail(&hlm.IllegalSizeSpecError {
+			Specifier: spec,
+			LibError: serr,
+			Location: &parser.Token.Location,
+		})
+		parser.Frame("'size' file filter", start)
+		return nil
+	}
+	parser.Next()
+	return hlm.NewSizeFileFilter(comparison, size)
+}
+
+func TopSizeFileFilter(parser *prs.Parser) hlv.FileFilter {
+	filter := ParseSizeFileFilter(parser)
+	if filter != nil {
+		return filter
+	} else {
+		return nil
+	}
+}
+
+func ParseAgeFileFilter(parser *prs.Parser, newer bool) *hlm.AgeFileFilter {
+	var initiator string
+	if newer {
+		initiator = "newerThan"
+	} else {
+		initiator = "olderThan"
+	}
+	if !parser.ExpectKeyword(initiator) {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	arise := &herr.AriseRef {
+		Text: "'" + initiator + "' file filter",
+		Location: start,
+	}
+	switch {
+		case parser.Token.Type == tok.T_STRING:
+			spec := parser.InterpolateString()
+			maxAge, derr := time.ParseDuration(spec)
+			if derr != nil {
+				parser.Fail(&hlm.IllegalDurationError {
+					Specifier: spec,
+					LibError: derr,
+					Location: &parser.Token.Location,
+				})
+				parser.Frame(arise.Text, start)
+				return nil
+			}
+			parser.Next()
+			return hlm.NewAgeFileFilter(newer, maxAge, arise)
+		case parser.IsArtifactRef(true):
+			ref := parser.ArtifactRef(arise, true)
+			if ref == nil {
+				parser.Frame(arise.Text, start)
+				return nil
+			}
+			filter := hlm.NewAgeReferenceFileFilter(newer, arise)
+			ref.InjectArtifact(parser.SpecState(), func(artifact abs.Artifact) {
+				filter.Reference = artifact
+			})
+			return filter
+		default:
+			parser.Die("string (duration) or artifact reference")
+			parser.Frame(arise.Text, start)
+			return nil
+	}
+}
+
+func TopOlderThanFileFilter(parser *prs.Parser) hlv.FileFilter {
+	filter := ParseAgeFileFilter(parser, false)
+	if filter != nil {
+		return filter
+	} else {
+		return nil
+	}
+}
+
+func TopNewerThanFileFilter(parser *prs.Parser) hlv.FileFilter {
+	filter := ParseAgeFileFilter(parser, true)
+	if filter != nil {
+		return filter
+	} else {
+		return nil
+	}
+}
+
+func ParseModeFileFilter(parser *prs.Parser) *hlm.ModeFileFilter {
+	if !parser.Expect(tok.T_NAME) {
+		return nil
+	}
+	var kind int
+	switch parser.Token.Text {
+		case "executable":
+			kind = hlm.MODEFILTER_EXECUTABLE
+		case "symlink":
+			kind = hlm.MODEFILTER_SYMLINK
+		case "empty":
+			kind = hlm.MODEFILTER_EMPTY
+		default:
+			parser.Die("'executable', 'symlink' or 'empty'")
+			return nil
+	}
+	parser.Next()
+	return hlm.NewModeFileFilter(kind)
+}
+
+func TopModeFileFilter(parser *prs.Parser) hlv.FileFilter {
+	filter := ParseModeFileFilter(parser)
+	if filter != nil {
+		return filter
+	} else {
+		return nil
+	}
+}
+
 func ParseAnyFileFilter(parser *prs.Parser) *hlm.AnyFileFilter {
 	if !parser.ExpectKeyword("any") {
 		return nil
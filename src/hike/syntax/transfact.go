@@ -44,6 +44,7 @@ func ParseCommandTransformFactory(parser *prs.Parser) *hlm.CommandTransformFacto
 	}
 	loud := false
 	suffixIsDestination := false
+	var streams gen.CommandStreams
 	for csx.IsExecOption(parser) {
 		switch parser.Token.Text {
 			case "loud":
@@ -53,7 +54,10 @@ func ParseCommandTransformFactory(parser *prs.Parser) *hlm.CommandTransformFacto
 				suffixIsDestination = true
 				parser.Next()
 			default:
-				panic("Unrecognized exec option: " + parser.Token.Text)
+				if !csx.ParseExecStreamOption(parser, &streams, "command transform factory", true) {
+					parser.Frame("command transform factory", start)
+					return nil
+				}
 		}
 	}
 	arise := &herr.AriseRef {
@@ -84,6 +88,7 @@ func ParseCommandTransformFactory(parser *prs.Parser) *hlm.CommandTransformFacto
 		loud,
 		suffixIsDestination,
 	)
+	exec.CommandStreams = streams
 	if parser.Token.Type != tok.T_RBRACE {
 		parser.Die("command option or '}'")
 		parser.Frame("command transform factory", start)
@@ -117,26 +122,17 @@ func ParseCopyTransformFactory(parser *prs.Parser) *hlm.CopyTransformFactory {
 		return hlm.NewCopyTransformFactory(false, specState.Config.TopDir, arise)
 	}
 	parser.Next()
-	rebaseFrom := specState.Config.TopDir
-	toDirectory := false
+	factory := hlm.NewCopyTransformFactory(false, specState.Config.TopDir, arise)
 	for {
 		switch {
-			case parser.IsKeyword("rebaseFrom"):
-				optloc := &parser.Token.Location
-				parser.Next()
-				if !parser.ExpectExp(tok.T_STRING, "pathname of base directory") {
-					parser.Frame("'rebaseFrom' copy option", optloc)
+			case IsCopyOption(parser):
+				if !ParseCopyOption(parser, &factory.CopyTransformBase) {
 					parser.Frame("copy transform factory", start)
 					return nil
 				}
-				rebaseFrom = specState.Config.RealPath(parser.InterpolateString())
-				parser.Next()
-			case parser.IsKeyword("toDirectory"):
-				toDirectory = true
-				parser.Next()
 			case parser.Token.Type == tok.T_RBRACE:
 				parser.Next()
-				return hlm.NewCopyTransformFactory(toDirectory, rebaseFrom, arise)
+				return factory
 			default:
 				parser.Die("copy option or '}'")
 				parser.Frame("copy transform factory", start)
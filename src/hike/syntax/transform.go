@@ -2,13 +2,18 @@ package syntax
 
 import (
 	"path"
+	"errors"
 	"regexp"
+	"strconv"
+	"archive/zip"
 	"path/filepath"
+	"compress/flate"
 	herr "hike/error"
 	tok "hike/token"
 	prs "hike/parser"
 	gen "hike/generic"
 	abs "hike/abstract"
+	con "hike/concrete"
 	csx "hike/comsyntax"
 	hlm "hike/hilvlimpl"
 )
@@ -46,6 +51,7 @@ func ParseCommandTransform(parser *prs.Parser) *gen.MultiCommandTransform {
 	}
 	loud := false
 	suffixIsDestination := false
+	var streams gen.CommandStreams
 	for csx.IsExecOption(parser) {
 		switch parser.Token.Text {
 			case "loud":
@@ -55,7 +61,10 @@ func ParseCommandTransform(parser *prs.Parser) *gen.MultiCommandTransform {
 				suffixIsDestination = true
 				parser.Next()
 			default:
-				panic("Unrecognized exec option: " + parser.Token.Text)
+				if !csx.ParseExecStreamOption(parser, &streams, "command transform", true) {
+					parser.Frame("command transform", start)
+					return nil
+				}
 		}
 	}
 	arise := &herr.AriseRef {
@@ -86,6 +95,7 @@ func ParseCommandTransform(parser *prs.Parser) *gen.MultiCommandTransform {
 		loud,
 		suffixIsDestination,
 	)
+	exec.CommandStreams = streams
 	specState := parser.SpecState()
 	for parser.IsArtifactRef(true) {
 		source := parser.ArtifactRef(&herr.AriseRef {
@@ -170,20 +180,11 @@ func ParseCopyTransform(parser *prs.Parser) *gen.CopyTransform {
 			haveOpts := false
 			for {
 				switch {
-					case parser.IsKeyword("rebaseFrom"):
-						optloc := &parser.Token.Location
-						parser.Next()
-						if !parser.ExpectExp(tok.T_STRING, "pathname of base directory") {
-							parser.Frame("'rebaseFrom' copy option", optloc)
+					case IsCopyOption(parser):
+						if !ParseCopyOption(parser, &transform.CopyTransformBase) {
 							parser.Frame("copy transform", start)
 							return nil
 						}
-						transform.RebaseFrom = specState.Config.RealPath(parser.InterpolateString())
-						parser.Next()
-						haveOpts = true
-					case parser.IsKeyword("toDirectory"):
-						transform.DestinationIsDir = true
-						parser.Next()
 						haveOpts = true
 					case parser.Token.Type == tok.T_RBRACE:
 						parser.Next()
@@ -205,6 +206,86 @@ func ParseCopyTransform(parser *prs.Parser) *gen.CopyTransform {
 	}
 }
 
+func IsCopyOption(parser *prs.Parser) bool {
+	return parser.IsKeyword("rebaseFrom") ||
+		parser.IsKeyword("toDirectory") ||
+		parser.IsKeyword("preserveSymlinks") ||
+		parser.IsKeyword("followSymlinks") ||
+		parser.IsKeyword("preserveTimes") ||
+		parser.IsKeyword("skipUnchanged") ||
+		parser.IsKeyword("rename") ||
+		parser.IsFileFilter()
+}
+
+func ParseCopyOption(parser *prs.Parser, options *gen.CopyTransformBase) bool {
+	switch {
+		case parser.IsKeyword("rebaseFrom"):
+			optloc := &parser.Token.Location
+			parser.Next()
+			if !parser.ExpectExp(tok.T_STRING, "pathname of base directory") {
+				parser.Frame("'rebaseFrom' copy option", optloc)
+				return false
+			}
+			options.RebaseFrom = parser.SpecState().Config.RealPath(parser.InterpolateString())
+			parser.Next()
+		case parser.IsKeyword("toDirectory"):
+			options.DestinationIsDir = true
+			parser.Next()
+		case parser.IsKeyword("preserveSymlinks"):
+			options.PreserveSymlinks = true
+			parser.Next()
+		case parser.IsKeyword("followSymlinks"):
+			options.PreserveSymlinks = false
+			parser.Next()
+		case parser.IsKeyword("preserveTimes"):
+			options.PreserveTimes = true
+			parser.Next()
+		case parser.IsKeyword("skipUnchanged"):
+			options.SkipUnchanged = true
+			parser.Next()
+		case parser.IsKeyword("rename"):
+			optloc := &parser.Token.Location
+			parser.Next()
+			if !parser.ExpectExp(tok.T_STRING, "basename regex") {
+				parser.Frame("'rename' copy option", optloc)
+				return false
+			}
+			regexText := parser.InterpolateString()
+			regex, rerr := regexp.Compile(regexText)
+			if rerr != nil {
+				parser.Fail(&hlm.IllegalRegexError {
+					Regex: regexText,
+					LibError: rerr,
+					PatternArise: &herr.AriseRef {
+						Text: "basename regex",
+						Location: &parser.Token.Location,
+					},
+				})
+				parser.Frame("'rename' copy option", optloc)
+				return false
+			}
+			parser.Next()
+			if !parser.ExpectExp(tok.T_STRING, "basename replacement") {
+				parser.Frame("'rename' copy option", optloc)
+				return false
+			}
+			options.BasenameRegex = regex
+			options.BasenameRegexText = regexText
+			options.BasenameReplacement = parser.InterpolateString()
+			parser.Next()
+		case parser.IsFileFilter():
+			filter := parser.FileFilter()
+			if filter == nil {
+				return false
+			}
+			options.AddFilter(filter)
+		default:
+			parser.Die("copy option")
+			return false
+	}
+	return true
+}
+
 func TopCopyTransform(parser *prs.Parser) abs.Transform {
 	transform := ParseCopyTransform(parser)
 	if transform != nil {
@@ -214,6 +295,167 @@ func TopCopyTransform(parser *prs.Parser) abs.Transform {
 	}
 }
 
+func ParseZipPiece(parser *prs.Parser, kind string, compressible bool, arise *herr.AriseRef) *gen.ZipPiece {
+	if !parser.ExpectKeyword("piece") {
+		return nil
+	}
+	what := kind + " piece"
+	pstart := &parser.Token.Location
+	parser.Next()
+	if !parser.Expect(tok.T_LBRACE) {
+		parser.Frame(what, pstart)
+		return nil
+	}
+	parser.Next()
+	specState := parser.SpecState()
+	var rebaseFrom, rebaseTo, basenameRegexText, basenameReplacement string
+	var basenameRegex *regexp.Regexp
+	var compressionMethod uint16 = zip.Deflate
+	compressionLevel := gen.ZIP_DEFAULT_LEVEL
+  pieceOpts:
+	for {
+		switch {
+			case parser.IsKeyword("from"):
+				optloc := &parser.Token.Location
+				parser.Next()
+				if !parser.ExpectExp(tok.T_STRING, "outer base directory") {
+					parser.Frame("'from' " + what + " option", optloc)
+					parser.Frame(what, pstart)
+					return nil
+				}
+				rebaseFrom = specState.Config.RealPath(parser.InterpolateString())
+				parser.Next()
+			case parser.IsKeyword("to"):
+				optloc := &parser.Token.Location
+				parser.Next()
+				if !parser.ExpectExp(tok.T_STRING, "inner base directory") {
+					parser.Frame("'to' " + what + " option", optloc)
+					parser.Frame(what, pstart)
+					return nil
+				}
+				rebaseTo = path.Clean(filepath.ToSlash(parser.InterpolateString()))
+				parser.Next()
+			case parser.IsKeyword("rename"):
+				optloc := &parser.Token.Location
+				parser.Next()
+				if !parser.ExpectExp(tok.T_STRING, "basename regex") {
+					parser.Frame("'rename' " + what + " option", optloc)
+					parser.Frame(what, pstart)
+					return nil
+				}
+				var rerr error
+				basenameRegexText = parser.InterpolateString()
+				basenameRegex, rerr = regexp.Compile(basenameRegexText)
+				if rerr != nil {
+					parser.Fail(&hlm.IllegalRegexError {
+						Regex: basenameRegexText,
+						LibError: rerr,
+						PatternArise: &herr.AriseRef {
+							Text: "basename regex",
+							Location: &parser.Token.Location,
+						},
+					})
+					parser.Frame("'rename' " + what + " option", optloc)
+					parser.Frame(what, pstart)
+					return nil
+				}
+				parser.Next()
+				if !parser.ExpectExp(tok.T_STRING, "basename replacement") {
+					parser.Frame("'rename' " + what + " option", optloc)
+					parser.Frame(what, pstart)
+					return nil
+				}
+				basenameReplacement = parser.InterpolateString()
+				parser.Next()
+			case compressible && parser.IsKeyword("method"):
+				optloc := &parser.Token.Location
+				parser.Next()
+				if !parser.ExpectExp(tok.T_STRING, "compression method") {
+					parser.Frame("'method' " + what + " option", optloc)
+					parser.Frame(what, pstart)
+					return nil
+				}
+				methodName := parser.InterpolateString()
+				var known bool
+				compressionMethod, known = gen.ZipCompressionMethod(methodName)
+				if !known {
+					parser.Fail(&gen.UnknownCompressionMethodError {
+						Method: methodName,
+						Location: &parser.Token.Location,
+					})
+					parser.Frame("'method' " + what + " option", optloc)
+					parser.Frame(what, pstart)
+					return nil
+				}
+				parser.Next()
+			case compressible && parser.IsKeyword("level"):
+				optloc := &parser.Token.Location
+				parser.Next()
+				if !parser.ExpectExp(tok.T_INT, "compression level") {
+					parser.Frame("'level' " + what + " option", optloc)
+					parser.Frame(what, pstart)
+					return nil
+				}
+				level, err := strconv.ParseInt(parser.Token.Text, 10, 32)
+				if err == nil && (level < flate.NoCompression || level > flate.BestCompression) {
+					err = errors.New("compression level must be between 0 and 9")
+				}
+				if err != nil {
+					parser.Fail(&con.IllegalIntegerLiteralError {
+						Specifier: parser.Token.Text,
+						LibError: err,
+						Location: &parser.Token.Location,
+					})
+					parser.Frame("'level' " + what + " option", optloc)
+					parser.Frame(what, pstart)
+					return nil
+				}
+				compressionLevel = int(level)
+				parser.Next()
+			default:
+				break pieceOpts
+		}
+	}
+	if len(rebaseFrom) == 0 {
+		rebaseFrom = specState.Config.TopDir
+	}
+	piece := &gen.ZipPiece {
+		RebaseFrom: rebaseFrom,
+		RebaseTo: rebaseTo,
+		BasenameRegex: basenameRegex,
+		BasenameRegexText: basenameRegexText,
+		BasenameReplacement: basenameReplacement,
+		CompressionMethod: compressionMethod,
+		CompressionLevel: compressionLevel,
+	}
+	haveSources := false
+	for {
+		switch {
+			case parser.IsArtifactRef(false):
+				aref := parser.ArtifactRef(arise, false)
+				if aref == nil {
+					parser.Frame(what, pstart)
+					return nil
+				}
+				aref.InjectArtifact(specState, func(artifact abs.Artifact) {
+					piece.AddSource(artifact)
+				})
+				haveSources = true
+			case parser.Token.Type == tok.T_RBRACE:
+				parser.Next()
+				return piece
+			default:
+				if haveSources {
+					parser.Die("artifact reference or '}'")
+				} else {
+					parser.Die(what + " option, artifact reference or '}'")
+				}
+				parser.Frame(what, pstart)
+				return nil
+		}
+	}
+}
+
 func ParseZipTransform(parser *prs.Parser) *gen.ZipTransform {
 	if !parser.ExpectKeyword("zip") {
 		return nil
@@ -244,129 +486,48 @@ func ParseZipTransform(parser *prs.Parser) *gen.ZipTransform {
 		Location: start,
 	}
 	transform := gen.NewZipTransform(description, arise, nil)
-	specState := parser.SpecState()
 	for {
 		switch {
-			case parser.IsKeyword("piece"):
-				pstart := &parser.Token.Location
-				parser.Next()
-				if !parser.Expect(tok.T_LBRACE) {
-					parser.Frame("zip piece", pstart)
-					parser.Frame("zip transform", start)
-					return nil
-				}
+			case parser.IsKeyword("fixedTime"):
+				transform.Options.FixedTime = true
 				parser.Next()
-				var rebaseFrom, rebaseTo, basenameRegexText, basenameReplacement string
-				var basenameRegex *regexp.Regexp
-			  pieceOpts:
-				for {
-					switch {
-						case parser.IsKeyword("from"):
-							optloc := &parser.Token.Location
-							parser.Next()
-							if !parser.ExpectExp(tok.T_STRING, "outer base directory") {
-								parser.Frame("'from' zip piece option", optloc)
-								parser.Frame("zip piece", pstart)
-								parser.Frame("zip transform", start)
-								return nil
-							}
-							rebaseFrom = specState.Config.RealPath(parser.InterpolateString())
-							parser.Next()
-						case parser.IsKeyword("to"):
-							optloc := &parser.Token.Location
-							parser.Next()
-							if !parser.ExpectExp(tok.T_STRING, "inner base directory") {
-								parser.Frame("'to' zip piece option", optloc)
-								parser.Frame("zip piece", pstart)
-								parser.Frame("zip transform", start)
-								return nil
-							}
-							rebaseTo = path.Clean(filepath.ToSlash(parser.InterpolateString()))
-							parser.Next()
-						case parser.IsKeyword("rename"):
-							optloc := &parser.Token.Location
-							parser.Next()
-							if !parser.ExpectExp(tok.T_STRING, "basename regex") {
-								parser.Frame("'rename' zip piece option", optloc)
-								parser.Frame("zip piece", pstart)
-								parser.Frame("zip transform", start)
-								return nil
-							}
-							var rerr error
-							basenameRegexText = parser.InterpolateString()
-							basenameRegex, rerr = regexp.Compile(basenameRegexText)
-							if rerr != nil {
-								parser.Fail(&hlm.IllegalRegexError {
-									Regex: basenameRegexText,
-									LibError: rerr,
-									PatternArise: &herr.AriseRef {
-										Text: "basename regex",
-										Location: &parser.Token.Location,
-									},
-								})
-								parser.Frame("'rename' zip piece option", optloc)
-								parser.Frame("zip piece", pstart)
-								parser.Frame("zip transform", start)
-								return nil
-							}
-							parser.Next()
-							if !parser.ExpectExp(tok.T_STRING, "basename replacement") {
-								parser.Frame("'rename' zip piece option", optloc)
-								parser.Frame("zip piece", pstart)
-								parser.Frame("zip transform", start)
-								return nil
-							}
-							basenameReplacement = parser.InterpolateString()
-							parser.Next()
-						default:
-							break pieceOpts
+				if parser.Token.Type == tok.T_STRING {
+					spec := parser.InterpolateString()
+					stamp, terr := gen.ParseTimestamp(spec)
+					if terr != nil {
+						parser.Fail(&gen.IllegalTimestampError {
+							Specifier: spec,
+							LibError: terr,
+							Location: &parser.Token.Location,
+						})
+						parser.Frame("zip transform", start)
+						return nil
 					}
+					transform.Options.Timestamp = stamp
+					transform.Options.TimestampText = spec
+					parser.Next()
 				}
-				if len(rebaseFrom) == 0 {
-					rebaseFrom = specState.Config.TopDir
-				}
-				piece := &gen.ZipPiece {
-					RebaseFrom: rebaseFrom,
-					RebaseTo: rebaseTo,
-					BasenameRegex: basenameRegex,
-					BasenameRegexText: basenameRegexText,
-					BasenameReplacement: basenameReplacement,
-				}
-				haveSources := false
-			  pieceArts:
-				for {
-					switch {
-						case parser.IsArtifactRef(false):
-							aref := parser.ArtifactRef(arise, false)
-							if aref == nil {
-								parser.Frame("zip piece", pstart)
-								parser.Frame("zip transform", start)
-								return nil
-							}
-							aref.InjectArtifact(specState, func(artifact abs.Artifact) {
-								piece.AddSource(artifact)
-							})
-							haveSources = true
-						case parser.Token.Type == tok.T_RBRACE:
-							parser.Next()
-							transform.AddPiece(piece)
-							break pieceArts
-						default:
-							if haveSources {
-								parser.Die("artifact reference or '}'")
-							} else {
-								parser.Die("zip piece option, artifact reference or '}'")
-							}
-							parser.Frame("zip piece", pstart)
-							parser.Frame("zip transform", start)
-							return nil
-					}
+			case parser.IsKeyword("sorted"):
+				transform.Options.Sorted = true
+				parser.Next()
+			case parser.IsKeyword("preserveModes"):
+				transform.Options.PreserveModes = true
+				parser.Next()
+			case parser.IsKeyword("skipDirectories"):
+				transform.Options.SkipDirectories = true
+				parser.Next()
+			case parser.IsKeyword("piece"):
+				piece := ParseZipPiece(parser, "zip", true, arise)
+				if piece == nil {
+					parser.Frame("zip transform", start)
+					return nil
 				}
+				transform.AddPiece(piece)
 			case parser.Token.Type == tok.T_RBRACE:
 				parser.Next()
 				return transform
 			default:
-				parser.Die("zip piece or '}'")
+				parser.Die("zip option, zip piece or '}'")
 				parser.Frame("zip transform", start)
 				return nil
 		}
@@ -382,6 +543,158 @@ func TopZipTransform(parser *prs.Parser) abs.Transform {
 	}
 }
 
+func ParseTarTransform(parser *prs.Parser) *gen.TarTransform {
+	if !parser.ExpectKeyword("tar") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	var description string
+	switch parser.Token.Type {
+		case tok.T_STRING:
+			description = parser.InterpolateString()
+			parser.Next()
+			if !parser.Expect(tok.T_LBRACE) {
+				parser.Frame("tar transform", start)
+				return nil
+			}
+		case tok.T_LBRACE:
+		default:
+			parser.Die("string (description) or '{'")
+			parser.Frame("tar transform", start)
+			return nil
+	}
+	parser.Next()
+	if len(description) == 0 {
+		description = "tar"
+	}
+	arise := &herr.AriseRef {
+		Text: "'tar' stanza",
+		Location: start,
+	}
+	var compress bool
+	if parser.IsKeyword("gzip") {
+		compress = true
+		parser.Next()
+	}
+	transform := gen.NewTarTransform(description, arise, nil, compress)
+	for {
+		switch {
+			case parser.IsKeyword("piece"):
+				piece := ParseZipPiece(parser, "tar", false, arise)
+				if piece == nil {
+					parser.Frame("tar transform", start)
+					return nil
+				}
+				transform.AddPiece(piece)
+			case parser.Token.Type == tok.T_RBRACE:
+				parser.Next()
+				return transform
+			default:
+				if transform.Compress || len(transform.Pieces) > 0 {
+					parser.Die("tar piece or '}'")
+				} else {
+					parser.Die("'gzip', tar piece or '}'")
+				}
+				parser.Frame("tar transform", start)
+				return nil
+		}
+	}
+}
+
+func TopTarTransform(parser *prs.Parser) abs.Transform {
+	transform := ParseTarTransform(parser)
+	if transform != nil {
+		return transform
+	} else {
+		return nil
+	}
+}
+
+func ParseUnzipValve(parser *prs.Parser) *hlm.UnzipValve {
+	if !parser.ExpectKeyword("valve") {
+		return nil
+	}
+	vstart := &parser.Token.Location
+	parser.Next()
+	if !parser.Expect(tok.T_LBRACE) {
+		parser.Frame("unzip valve", vstart)
+		return nil
+	}
+	parser.Next()
+	valve := &hlm.UnzipValve{}
+	for {
+		switch {
+			case parser.Token.Type == tok.T_RBRACE:
+				parser.Next()
+				return valve
+			case parser.IsKeyword("from"):
+				optloc := &parser.Token.Location
+				parser.Next()
+				if !parser.ExpectExp(tok.T_STRING, "inner base directory") {
+					parser.Frame("'from' unzip valve option", optloc)
+					parser.Frame("unzip valve", vstart)
+					return nil
+				}
+				valve.RebaseFrom = path.Clean("/" + filepath.ToSlash(parser.InterpolateString()))[1:]
+				parser.Next()
+			case parser.IsKeyword("to"):
+				optloc := &parser.Token.Location
+				parser.Next()
+				if !parser.ExpectExp(tok.T_STRING, "outer base directory") {
+					parser.Frame("'to' unzip valve option", optloc)
+					parser.Frame("unzip valve", vstart)
+					return nil
+				}
+				valve.RebaseTo = filepath.Clean(filepath.FromSlash(parser.InterpolateString()))
+				parser.Next()
+			case parser.IsKeyword("rename"):
+				optloc := &parser.Token.Location
+				parser.Next()
+				if !parser.ExpectExp(tok.T_STRING, "basename regex") {
+					parser.Frame("'rename' unzip valve option", optloc)
+					parser.Frame("unzip valve", vstart)
+					return nil
+				}
+				var rerr error
+				valve.BasenameRegexText = parser.InterpolateString()
+				valve.BasenameRegex, rerr = regexp.Compile(valve.BasenameRegexText)
+				if rerr != nil {
+					parser.Fail(&hlm.IllegalRegexError {
+						Regex: valve.BasenameRegexText,
+						LibError: rerr,
+						PatternArise: &herr.AriseRef {
+							Text: "basename regex",
+							Location: &parser.Token.Location,
+						},
+					})
+					parser.Frame("'rename' unzip valve option", optloc)
+					parser.Frame("unzip valve", vstart)
+					return nil
+				}
+				parser.Next()
+				if !parser.ExpectExp(tok.T_STRING, "basename replacement") {
+					parser.Frame("'rename' unzip valve option", optloc)
+					parser.Frame("unzip valve", vstart)
+					return nil
+				}
+				valve.BasenameReplacement = parser.InterpolateString()
+				parser.Next()
+			case parser.IsFileFilter():
+				filter := parser.FileFilter()
+				if filter == nil {
+					parser.Frame("unzip valve", vstart)
+					return nil
+				}
+				valve.AddFilter(filter)
+			default:
+				parser.Die("unzip valve option or file filter")
+				parser.Frame("unzip valve", vstart)
+				return nil
+		}
+	}
+}
+
 func ParseUnzipTransform(parser *prs.Parser) *hlm.UnzipTransform {
 	if !parser.ExpectKeyword("unzip") {
 		return nil
@@ -413,6 +726,22 @@ func ParseUnzipTransform(parser *prs.Parser) *hlm.UnzipTransform {
 	}
 	specState := parser.SpecState()
 	transform := hlm.NewUnzipTransform(description, arise, nil, specState.Config.TopDir, nil)
+  unzipOpts:
+	for {
+		switch {
+			case parser.IsKeyword("preserveModes"):
+				transform.Options.PreserveModes = true
+			case parser.IsKeyword("preserveTimes"):
+				transform.Options.PreserveTimes = true
+			case parser.IsKeyword("symlinks"):
+				transform.Options.Symlinks = true
+			case parser.IsKeyword("skipIdentical"):
+				transform.Options.SkipIdentical = true
+			default:
+				break unzipOpts
+		}
+		parser.Next()
+	}
 	for {
 		aref := parser.ArtifactRef(arise, false)
 		if aref == nil {
@@ -428,95 +757,13 @@ func ParseUnzipTransform(parser *prs.Parser) *hlm.UnzipTransform {
 	}
 	haveValves := false
 	for parser.IsKeyword("valve") {
-		vstart := &parser.Token.Location
-		parser.Next()
-		if !parser.Expect(tok.T_LBRACE) {
-			parser.Frame("unzip valve", vstart)
+		valve := ParseUnzipValve(parser)
+		if valve == nil {
 			parser.Frame("unzip transform", start)
 			return nil
 		}
-		parser.Next();
-		valve := &hlm.UnzipValve{}
-	  valveOpts:
-		for {
-			switch {
-				case parser.Token.Type == tok.T_RBRACE:
-					parser.Next()
-					transform.AddValve(valve)
-					haveValves = true
-					break valveOpts
-				case parser.IsKeyword("from"):
-					optloc := &parser.Token.Location
-					parser.Next()
-					if !parser.ExpectExp(tok.T_STRING, "inner base directory") {
-						parser.Frame("'from' unzip valve option", optloc)
-						parser.Frame("unzip valve", vstart)
-						parser.Frame("unzip transform", start)
-						return nil
-					}
-					valve.RebaseFrom = path.Clean("/" + filepath.ToSlash(parser.InterpolateString()))[1:]
-					parser.Next()
-				case parser.IsKeyword("to"):
-					optloc := &parser.Token.Location
-					parser.Next()
-					if !parser.ExpectExp(tok.T_STRING, "outer base directory") {
-						parser.Frame("'to' unzip valve option", optloc)
-						parser.Frame("unzip valve", vstart)
-						parser.Frame("unzip transform", start)
-						return nil
-					}
-					valve.RebaseTo = filepath.Clean(filepath.FromSlash(parser.InterpolateString()))
-					parser.Next()
-				case parser.IsKeyword("rename"):
-					optloc := &parser.Token.Location
-					parser.Next()
-					if !parser.ExpectExp(tok.T_STRING, "basename regex") {
-						parser.Frame("'rename' unzip valve option", optloc)
-						parser.Frame("unzip valve", vstart)
-						parser.Frame("unzip transform", start)
-						return nil
-					}
-					var rerr error
-					valve.BasenameRegexText = parser.InterpolateString()
-					valve.BasenameRegex, rerr = regexp.Compile(valve.BasenameRegexText)
-					if rerr != nil {
-						parser.Fail(&hlm.IllegalRegexError {
-							Regex: valve.BasenameRegexText,
-							LibError: rerr,
-							PatternArise: &herr.AriseRef {
-								Text: "basename regex",
-								Location: &parser.Token.Location,
-							},
-						})
-						parser.Frame("'rename' unzip valve option", optloc)
-						parser.Frame("unzip valve", vstart)
-						parser.Frame("unzip transform", start)
-						return nil
-					}
-					parser.Next()
-					if !parser.ExpectExp(tok.T_STRING, "basename replacement") {
-						parser.Frame("'rename' unzip valve option", optloc)
-						parser.Frame("unzip valve", vstart)
-						parser.Frame("unzip transform", start)
-						return nil
-					}
-					valve.BasenameReplacement = parser.InterpolateString()
-					parser.Next()
-				case parser.IsFileFilter():
-					filter := parser.FileFilter()
-					if filter == nil {
-						parser.Frame("unzip valve", vstart)
-						parser.Frame("unzip transform", start)
-						return nil
-					}
-					valve.AddFilter(filter)
-				default:
-					parser.Die("unzip valve option or file filter")
-					parser.Frame("unzip valve", vstart)
-					parser.Frame("unzip transform", start)
-					return nil
-			}
-		}
+		transform.AddValve(valve)
+		haveValves = true
 	}
 	if parser.Token.Type != tok.T_RBRACE {
 		if haveValves {
@@ -543,6 +790,542 @@ func TopUnzipTransform(parser *prs.Parser) abs.Transform {
 	}
 }
 
+func ParseUntarTransform(parser *prs.Parser) *hlm.UntarTransform {
+	if !parser.ExpectKeyword("untar") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	var description string
+	switch parser.Token.Type {
+		case tok.T_STRING:
+			description = parser.InterpolateString()
+			parser.Next()
+			if !parser.Expect(tok.T_LBRACE) {
+				parser.Frame("untar transform", start)
+				return nil
+			}
+		case tok.T_LBRACE:
+		default:
+			parser.Die("string (description) or '{'")
+			parser.Frame("untar transform", start)
+			return nil
+	}
+	parser.Next()
+	if len(description) == 0 {
+		description = "untar"
+	}
+	arise := &herr.AriseRef {
+		Text: "'untar' stanza",
+		Location: start,
+	}
+	specState := parser.SpecState()
+	transform := hlm.NewUntarTransform(description, arise, nil, specState.Config.TopDir, nil)
+	for {
+		aref := parser.ArtifactRef(arise, false)
+		if aref == nil {
+			parser.Frame("untar transform", start)
+			return nil
+		}
+		aref.InjectArtifact(specState, func(artifact abs.Artifact) {
+			transform.AddArchive(artifact)
+		})
+		if !parser.IsArtifactRef(false) {
+			break
+		}
+	}
+	haveValves := false
+	for parser.IsKeyword("valve") {
+		valve := ParseUnzipValve(parser)
+		if valve == nil {
+			parser.Frame("untar transform", start)
+			return nil
+		}
+		transform.AddValve(valve)
+		haveValves = true
+	}
+	if parser.Token.Type != tok.T_RBRACE {
+		if haveValves {
+			parser.Die("unzip valve or '}'")
+		} else {
+			parser.Die("artifact reference, unzip valve or '}'")
+		}
+		parser.Frame("untar transform", start)
+		return nil
+	}
+	parser.Next()
+	if !haveValves {
+		transform.AddValve(&hlm.UnzipValve{})
+	}
+	return transform
+}
+
+func TopUntarTransform(parser *prs.Parser) abs.Transform {
+	transform := ParseUntarTransform(parser)
+	if transform != nil {
+		return transform
+	} else {
+		return nil
+	}
+}
+
+func ParseLinkTransform(parser *prs.Parser) *gen.LinkTransform {
+	if !parser.ExpectKeyword("link") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	arise := &herr.AriseRef {
+		Text: "'link' stanza",
+		Location: start,
+	}
+	specState := parser.SpecState()
+	transform := gen.NewLinkTransform(arise, nil, false, false, specState.Config.TopDir)
+	switch {
+		case parser.IsArtifactRef(false):
+			source := parser.ArtifactRef(arise, false)
+			if source == nil {
+				parser.Frame("link transform", start)
+				return nil
+			}
+			source.InjectArtifact(specState, func(artifact abs.Artifact) {
+				transform.Source = artifact
+			})
+			return transform
+		case parser.Token.Type == tok.T_LBRACE:
+			parser.Next()
+			source := parser.ArtifactRef(arise, false)
+			if source == nil {
+				parser.Frame("link transform", start)
+				return nil
+			}
+			source.InjectArtifact(specState, func(artifact abs.Artifact) {
+				transform.Source = artifact
+			})
+		  opts:
+			for parser.Token.Type == tok.T_NAME {
+				switch parser.Token.Text {
+					case "hard":
+						transform.Hard = true
+					case "symbolic":
+						transform.Hard = false
+					case "relative":
+						transform.Relative = true
+					case "absolute":
+						transform.Relative = false
+					default:
+						break opts
+				}
+				parser.Next()
+			}
+			if !parser.ExpectExp(tok.T_RBRACE, "link option or '}'") {
+				parser.Frame("link transform", start)
+				return nil
+			}
+			parser.Next()
+			return transform
+		default:
+			parser.Die("artifact reference or '{'")
+			parser.Frame("link transform", start)
+			return nil
+	}
+}
+
+func TopLinkTransform(parser *prs.Parser) abs.Transform {
+	transform := ParseLinkTransform(parser)
+	if transform != nil {
+		return transform
+	} else {
+		return nil
+	}
+}
+
+func ParseSubstituteTransform(parser *prs.Parser) *hlm.SubstituteTransform {
+	if !parser.ExpectKeyword("substitute") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	arise := &herr.AriseRef {
+		Text: "'substitute' stanza",
+		Location: start,
+	}
+	specState := parser.SpecState()
+	transform := hlm.NewSubstituteTransform(arise, nil, specState, false, specState.Config.TopDir)
+	switch {
+		case parser.IsArtifactRef(false):
+			source := parser.ArtifactRef(arise, false)
+			if source == nil {
+				parser.Frame("substitute transform", start)
+				return nil
+			}
+			source.InjectArtifact(specState, func(artifact abs.Artifact) {
+				transform.Source = artifact
+			})
+			return transform
+		case parser.Token.Type == tok.T_LBRACE:
+			parser.Next()
+			source := parser.ArtifactRef(arise, false)
+			if source == nil {
+				parser.Frame("substitute transform", start)
+				return nil
+			}
+			source.InjectArtifact(specState, func(artifact abs.Artifact) {
+				transform.Source = artifact
+			})
+			if parser.IsKeyword("template") {
+				transform.GoTemplate = true
+				parser.Next()
+			}
+			if !parser.ExpectExp(tok.T_RBRACE, "'template' or '}'") {
+				parser.Frame("substitute transform", start)
+				return nil
+			}
+			parser.Next()
+			return transform
+		default:
+			parser.Die("artifact reference or '{'")
+			parser.Frame("substitute transform", start)
+			return nil
+	}
+}
+
+func TopSubstituteTransform(parser *prs.Parser) abs.Transform {
+	transform := ParseSubstituteTransform(parser)
+	if transform != nil {
+		return transform
+	} else {
+		return nil
+	}
+}
+
+func ParseConcatTransform(parser *prs.Parser) *gen.ConcatTransform {
+	if !parser.ExpectKeyword("concat") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	arise := &herr.AriseRef {
+		Text: "'concat' stanza",
+		Location: start,
+	}
+	specState := parser.SpecState()
+	transform := gen.NewConcatTransform(arise, specState.Config.TopDir)
+	switch {
+		case parser.IsArtifactRef(false):
+			source := parser.ArtifactRef(arise, false)
+			if source == nil {
+				parser.Frame("concat transform", start)
+				return nil
+			}
+			source.InjectArtifact(specState, func(artifact abs.Artifact) {
+				transform.AddSource(artifact)
+			})
+			return transform
+		case parser.Token.Type == tok.T_LBRACE:
+			parser.Next()
+			initialSource := parser.ArtifactRef(arise, false)
+			if initialSource == nil {
+				parser.Frame("concat transform", start)
+				return nil
+			}
+			initialSource.InjectArtifact(specState, func(artifact abs.Artifact) {
+				transform.AddSource(artifact)
+			})
+			for parser.IsArtifactRef(false) {
+				nextSource := parser.ArtifactRef(arise, false)
+				if nextSource == nil {
+					parser.Frame("concat transform", start)
+					return nil
+				}
+				nextSource.InjectArtifact(specState, func(artifact abs.Artifact) {
+					transform.AddSource(artifact)
+				})
+			}
+			haveOpts := false
+		  opts:
+			for parser.Token.Type == tok.T_NAME {
+				var sink *string
+				switch parser.Token.Text {
+					case "separator":
+						sink = &transform.Separator
+					case "header":
+						sink = &transform.Header
+					case "footer":
+						sink = &transform.Footer
+					default:
+						break opts
+				}
+				optloc := &parser.Token.Location
+				what := parser.Token.Text
+				parser.Next()
+				if !parser.ExpectExp(tok.T_STRING, what + " text") {
+					parser.Frame("'" + what + "' concat option", optloc)
+					parser.Frame("concat transform", start)
+					return nil
+				}
+				*sink = parser.InterpolateString()
+				parser.Next()
+				haveOpts = true
+			}
+			if parser.Token.Type != tok.T_RBRACE {
+				if haveOpts {
+					parser.Die("concat option or '}'")
+				} else {
+					parser.Die("artifact reference, concat option or '}'")
+				}
+				parser.Frame("concat transform", start)
+				return nil
+			}
+			parser.Next()
+			return transform
+		default:
+			parser.Die("artifact reference or '{'")
+			parser.Frame("concat transform", start)
+			return nil
+	}
+}
+
+func TopConcatTransform(parser *prs.Parser) abs.Transform {
+	transform := ParseConcatTransform(parser)
+	if transform != nil {
+		return transform
+	} else {
+		return nil
+	}
+}
+
+func ParseWriteTransform(parser *prs.Parser) *gen.WriteTransform {
+	if !parser.ExpectKeyword("write") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	if !parser.ExpectExp(tok.T_STRING, "file content") {
+		parser.Frame("write transform", start)
+		return nil
+	}
+	content := parser.InterpolateString()
+	parser.Next()
+	arise := &herr.AriseRef {
+		Text: "'write' stanza",
+		Location: start,
+	}
+	return gen.NewWriteTransform(arise, content, parser.SpecState().Config.TopDir)
+}
+
+func TopWriteTransform(parser *prs.Parser) abs.Transform {
+	transform := ParseWriteTransform(parser)
+	if transform != nil {
+		return transform
+	} else {
+		return nil
+	}
+}
+
+func ParseChecksumsTransform(parser *prs.Parser) *gen.ChecksumsTransform {
+	if !parser.ExpectKeyword("checksums") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	arise := &herr.AriseRef {
+		Text: "'checksums' stanza",
+		Location: start,
+	}
+	specState := parser.SpecState()
+	transform := gen.NewChecksumsTransform(arise, "sha256", specState.Config.TopDir)
+	switch {
+		case parser.IsArtifactRef(false):
+			source := parser.ArtifactRef(arise, false)
+			if source == nil {
+				parser.Frame("checksums transform", start)
+				return nil
+			}
+			source.InjectArtifact(specState, func(artifact abs.Artifact) {
+				transform.AddSource(artifact)
+			})
+			return transform
+		case parser.Token.Type == tok.T_LBRACE:
+			parser.Next()
+			initialSource := parser.ArtifactRef(arise, false)
+			if initialSource == nil {
+				parser.Frame("checksums transform", start)
+				return nil
+			}
+			initialSource.InjectArtifact(specState, func(artifact abs.Artifact) {
+				transform.AddSource(artifact)
+			})
+			for parser.IsArtifactRef(false) {
+				nextSource := parser.ArtifactRef(arise, false)
+				if nextSource == nil {
+					parser.Frame("checksums transform", start)
+					return nil
+				}
+				nextSource.InjectArtifact(specState, func(artifact abs.Artifact) {
+					transform.AddSource(artifact)
+				})
+			}
+			haveOpts := false
+		  opts:
+			for parser.Token.Type == tok.T_NAME {
+				switch parser.Token.Text {
+					case "sha256", "sha512":
+						transform.Algorithm = parser.Token.Text
+						parser.Next()
+					case "rebaseFrom":
+						optloc := &parser.Token.Location
+						parser.Next()
+						if !parser.ExpectExp(tok.T_STRING, "pathname of base directory") {
+							parser.Frame("'rebaseFrom' checksums option", optloc)
+							parser.Frame("checksums transform", start)
+							return nil
+						}
+						transform.RebaseFrom = specState.Config.RealPath(parser.InterpolateString())
+						parser.Next()
+					default:
+						break opts
+				}
+				haveOpts = true
+			}
+			if parser.Token.Type != tok.T_RBRACE {
+				if haveOpts {
+					parser.Die("checksums option or '}'")
+				} else {
+					parser.Die("artifact reference, checksums option or '}'")
+				}
+				parser.Frame("checksums transform", start)
+				return nil
+			}
+			parser.Next()
+			return transform
+		default:
+			parser.Die("artifact reference or '{'")
+			parser.Frame("checksums transform", start)
+			return nil
+	}
+}
+
+func TopChecksumsTransform(parser *prs.Parser) abs.Transform {
+	transform := ParseChecksumsTransform(parser)
+	if transform != nil {
+		return transform
+	} else {
+		return nil
+	}
+}
+
+func ParseSyncTransform(parser *prs.Parser) *hlm.SyncTransform {
+	if !parser.ExpectKeyword("sync") {
+		return nil
+	}
+	start := &parser.Token.Location
+	parser.Next()
+	arise := &herr.AriseRef {
+		Text: "'sync' stanza",
+		Location: start,
+	}
+	specState := parser.SpecState()
+	transform := hlm.NewSyncTransform(arise, specState.Config.TopDir, specState.Config.TopDir)
+	switch {
+		case parser.IsArtifactRef(false):
+			source := parser.ArtifactRef(arise, false)
+			if source == nil {
+				parser.Frame("sync transform", start)
+				return nil
+			}
+			source.InjectArtifact(specState, func(artifact abs.Artifact) {
+				transform.AddSource(artifact)
+			})
+			return transform
+		case parser.Token.Type == tok.T_LBRACE:
+			parser.Next()
+			initialSource := parser.ArtifactRef(arise, false)
+			if initialSource == nil {
+				parser.Frame("sync transform", start)
+				return nil
+			}
+			initialSource.InjectArtifact(specState, func(artifact abs.Artifact) {
+				transform.AddSource(artifact)
+			})
+			for parser.IsArtifactRef(false) {
+				nextSource := parser.ArtifactRef(arise, false)
+				if nextSource == nil {
+					parser.Frame("sync transform", start)
+					return nil
+				}
+				nextSource.InjectArtifact(specState, func(artifact abs.Artifact) {
+					transform.AddSource(artifact)
+				})
+			}
+			haveOpts := false
+		  opts:
+			for parser.Token.Type == tok.T_NAME {
+				switch parser.Token.Text {
+					case "rebaseFrom":
+						optloc := &parser.Token.Location
+						parser.Next()
+						if !parser.ExpectExp(tok.T_STRING, "pathname of base directory") {
+							parser.Frame("'rebaseFrom' sync option", optloc)
+							parser.Frame("sync transform", start)
+							return nil
+						}
+						transform.RebaseFrom = specState.Config.RealPath(parser.InterpolateString())
+						parser.Next()
+					case "preserveSymlinks":
+						transform.PreserveSymlinks = true
+						parser.Next()
+					case "followSymlinks":
+						transform.PreserveSymlinks = false
+						parser.Next()
+					case "exclude":
+						optloc := &parser.Token.Location
+						parser.Next()
+						if !parser.IsFileFilter() {
+							parser.Die("file filter")
+							parser.Frame("'exclude' sync option", optloc)
+							parser.Frame("sync transform", start)
+							return nil
+						}
+						filter := parser.FileFilter()
+						if filter == nil {
+							parser.Frame("'exclude' sync option", optloc)
+							parser.Frame("sync transform", start)
+							return nil
+						}
+						transform.AddExclude(filter)
+					default:
+						break opts
+				}
+				haveOpts = true
+			}
+			if parser.Token.Type != tok.T_RBRACE {
+				if haveOpts {
+					parser.Die("sync option or '}'")
+				} else {
+					parser.Die("artifact reference, sync option or '}'")
+				}
+				parser.Frame("sync transform", start)
+				return nil
+			}
+			parser.Next()
+			return transform
+		default:
+			parser.Die("artifact reference or '{'")
+			parser.Frame("sync transform", start)
+			return nil
+	}
+}
+
+func TopSyncTransform(parser *prs.Parser) abs.Transform {
+	transform := ParseSyncTransform(parser)
+	if transform != nil {
+		return transform
+	} else {
+		return nil
+	}
+}
+
 func ParseMkdirTransform(parser *prs.Parser) *gen.MkdirTransform {
 	if !parser.ExpectKeyword("mkdir") {
 		return nil
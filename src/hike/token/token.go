@@ -22,6 +22,7 @@ type Token struct {
 	Location loc.Location
 	Type Type
 	Text string
+	Raw bool
 }
 
 func EscapeRuneTo(c rune, quote bool, delimiter rune, sink *strings.Builder) (err error) {
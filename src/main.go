@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"flag"
 	"time"
+	"strings"
 	"path/filepath"
 	herr "hike/error"
 	spc "hike/spec"
@@ -12,6 +13,8 @@ import (
 	rdr "hike/reader"
 	prs "hike/parser"
 	abs "hike/abstract"
+	syn "hike/syntax"
+	rpo "hike/repository"
 )
 
 import _ "hike/concrete"
@@ -54,6 +57,17 @@ func fileExists(path string) (exists bool, err error) {
 	return
 }
 
+type dependKeyList []string
+
+func (keys *dependKeyList) String() string {
+	return strings.Join(*keys, ",")
+}
+
+func (keys *dependKeyList) Set(value string) error {
+	*keys = append(*keys, value)
+	return nil
+}
+
 func main() {
 	var hikefileName string
 	const hikefileUsage = "Filename of hikefile to read for root project."
@@ -66,6 +80,12 @@ func main() {
 	var dumpStruct bool
 	const dumpStructUsage = "Dump artifact/transform structure (and quit if no goal given)."
 	flag.BoolVar(&dumpStruct, "dump", false, dumpStructUsage)
+	var updateLock bool
+	const updateLockUsage = "Re-resolve all locked dependencies."
+	flag.BoolVar(&updateLock, "update-lock", false, updateLockUsage)
+	var updateLockKeys dependKeyList
+	const updateLockKeyUsage = "Re-resolve only the locked dependency with the given key (may be repeated)."
+	flag.Var(&updateLockKeys, "update-lock-key", updateLockKeyUsage)
 	flag.Parse()
 	noDefaultBuild := dumpStruct
 	// find hikefile
@@ -109,11 +129,30 @@ func main() {
 		CurrentHikefile: hikefilePath,
 	}
 	rootState := spc.NewState(config, nil, "")
+	lock, err := rpo.ReadLockfile(filepath.Join(topDir, rpo.LOCKFILE_NAME))
+	if err != nil {
+		die(err)
+	}
+	if updateLock {
+		lock.UpdateAll()
+	}
+	for _, dependKey := range updateLockKeys {
+		lock.UpdateKey(dependKey)
+	}
+	rootState.SetVersionLock(lock)
 	knownStructures := prs.NewKnownStructures()
 	knw.RegisterAllKnownStructures(knownStructures)
 	// compile hikefile
 	fullStartTime := time.Now()
-	err := rdr.ReadFile(hikefilePath, knownStructures, rootState)
+	err = rdr.ReadFile(hikefilePath, knownStructures, rootState)
+	if err != nil {
+		die(err)
+	}
+	err = syn.ResolveDependencies(rootState, knownStructures)
+	if err != nil {
+		die(err)
+	}
+	err = lock.Write()
 	if err != nil {
 		die(err)
 	}